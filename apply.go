@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/haboustak/linkctl/internal/networkd"
+)
+
+var cmdApply = &Command{
+	Name: "apply",
+	Run:  apply,
+	Usage: `Usage:
+    linkctl [-h] apply [-prune] -f FILE
+
+Change links to match a manifest, enabling, disabling and renaming them
+and setting their descriptions. Each change is printed before it is made
+and nothing is printed when the links already match. The changes are made
+together, if any of them fails none are kept.
+
+A manifest is a YAML or JSON file listing links by their unit file, or
+by name when the unit is left out. Settings that are not listed are not
+changed, and neither are links unless -prune is given, in which case
+enabled links that are not listed are disabled.
+
+    links:
+      - unit: 50-eth0.test.300.netdev
+        name: vlan300
+        status: enabled
+        description: Storage network
+      - name: othernet
+        status: disabled
+
+Status is one of enabled, enabled-runtime or disabled. Any other status,
+such as user-defined in an exported manifest, is only checked. A name can
+only be changed for links listed by unit.
+
+Options:
+    -f FILE     manifest to apply, - to read it from stdin
+    -h          show this help
+    -prune      disable enabled links that are not in the manifest
+`,
+}
+
+var (
+	applyFile  string
+	applyPrune bool
+)
+
+func init() {
+	cmdApply.Flags.StringVar(&applyFile, "f", "", "manifest to apply")
+	cmdApply.Flags.BoolVar(&applyPrune, "prune", false, "disable links not in the manifest")
+}
+
+// linkChange is a single change needed to bring a link to the state of
+// its manifest entry
+type linkChange struct {
+	name   string
+	action string
+	apply  func() error
+}
+
+func apply(self *Command) error {
+	if applyFile == "" {
+		return errors.New("You must provide the manifest to apply with -f")
+	}
+	if len(self.Flags.Args()) > 0 {
+		return fmt.Errorf("Unexpected argument %s", self.Flags.Arg(0))
+	}
+
+	manifest, err := readManifest(applyFile)
+	if err != nil {
+		return err
+	}
+
+	// Every entry is checked before any change is made
+	var changes []linkChange
+	listed := make(map[*networkd.NetDev]bool)
+	for _, link := range manifest.Links {
+		netdev, err := link.find()
+		if err != nil {
+			return err
+		}
+		listed[netdev] = true
+
+		linkChanges, err := planLinkChanges(link, netdev)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, linkChanges...)
+	}
+
+	if applyPrune {
+		changes = append(changes, pruneChanges(listed)...)
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+
+	for _, change := range changes {
+		fmt.Printf("%s: %s\n", change.name, change.action)
+	}
+
+	return networkd.Apply(func() error {
+		for _, change := range changes {
+			if err := change.apply(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func planLinkChanges(link *ManifestLink, netdev *networkd.NetDev) ([]linkChange, error) {
+	var changes []linkChange
+	name := netdev.Name
+
+	if link.Unit != "" && link.Name != "" && link.Name != netdev.Name {
+		change := linkChange{
+			name:   name,
+			action: "rename to " + link.Name,
+			apply: func() error {
+				return setName(netdev, link.Name)
+			},
+		}
+		// The unit's own name is restored by removing the rename drop-in
+		if link.Name == netdev.Unit.Get("NetDev", "Name") {
+			change.action = "reset name to " + link.Name
+			change.apply = func() error {
+				return clearName(netdev)
+			}
+		}
+		changes = append(changes, change)
+	}
+
+	if link.Description != nil && *link.Description != netdev.Description {
+		action := fmt.Sprintf("set description to %q", *link.Description)
+		if *link.Description == "" {
+			action = "clear description"
+		}
+		changes = append(changes, linkChange{
+			name:   name,
+			action: action,
+			apply: func() error {
+				return netdev.SetDescription(*link.Description)
+			},
+		})
+	}
+
+	status := networkd.LinkStatus(link.Status)
+	if status == "" || status == netdev.Status {
+		return changes, nil
+	}
+
+	switch status {
+	case networkd.LinkEnabled, networkd.LinkEnabledRuntime, networkd.LinkDisabled:
+	default:
+		return nil, fmt.Errorf("The link %s is %s and cannot be made %s", name, netdev.Status, status)
+	}
+
+	switch netdev.Status {
+	case networkd.LinkEnabled, networkd.LinkEnabledRuntime:
+		if status != networkd.LinkDisabled {
+			return nil, fmt.Errorf("The link %s is %s, disable it before it can be %s",
+				name, netdev.Status, status)
+		}
+	case networkd.LinkDisabled:
+	default:
+		return nil, fmt.Errorf("The link %s is %s and cannot be %s", name, netdev.Status, status)
+	}
+
+	change := linkChange{name: name}
+	switch status {
+	case networkd.LinkEnabled:
+		change.action = "enable"
+		change.apply = netdev.Enable
+	case networkd.LinkEnabledRuntime:
+		change.action = "enable until the next reboot"
+		change.apply = netdev.EnableRuntime
+	case networkd.LinkDisabled:
+		change.action = "disable"
+		change.apply = netdev.Disable
+		if netdev.Status == networkd.LinkEnabledRuntime {
+			change.apply = netdev.DisableRuntime
+		}
+	}
+
+	return append(changes, change), nil
+}
+
+// pruneChanges disables the enabled links that are not listed
+func pruneChanges(listed map[*networkd.NetDev]bool) []linkChange {
+	var changes []linkChange
+	for _, netdev := range networkd.ListNetDev(true) {
+		if listed[netdev] {
+			continue
+		}
+
+		switch netdev.Status {
+		case networkd.LinkEnabled:
+			changes = append(changes, linkChange{
+				name:   netdev.Name,
+				action: "disable",
+				apply:  netdev.Disable,
+			})
+		case networkd.LinkEnabledRuntime:
+			changes = append(changes, linkChange{
+				name:   netdev.Name,
+				action: "disable",
+				apply:  netdev.DisableRuntime,
+			})
+		}
+	}
+	return changes
+}
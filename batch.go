@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/haboustak/linkctl/internal/networkd"
+)
+
+// resolveLinks expands link names and glob patterns. A pattern only matches
+// the links eligible for the operation, e.g. disabled links for enable,
+// while a link named explicitly is always included so that the operation
+// can report why it does not apply.
+func resolveLinks(patterns []string, eligible func(netdev *networkd.NetDev) bool) ([]*networkd.NetDev, error) {
+	var links []*networkd.NetDev
+	found := make(map[string]bool)
+
+	add := func(netdev *networkd.NetDev) {
+		if !found[netdev.Name] {
+			found[netdev.Name] = true
+			links = append(links, netdev)
+		}
+	}
+
+	for _, pattern := range patterns {
+		if !strings.ContainsAny(pattern, "*?[") {
+			netdev, ok := networkd.GetNetDev(pattern)
+			if !ok {
+				return nil, fmt.Errorf("No link with the name %s", pattern)
+			}
+			add(netdev)
+			continue
+		}
+
+		matched := false
+		for _, netdev := range networkd.ListNetDev(true) {
+			ok, err := filepath.Match(pattern, netdev.Name)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid link pattern %s: %w", pattern, err)
+			}
+			if ok && eligible(netdev) {
+				add(netdev)
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("No links match %s", pattern)
+		}
+	}
+
+	return links, nil
+}
+
+// printResults reports the outcome for each link and returns the names of
+// the links that were changed.
+func printResults(results []networkd.Result, verb string) ([]string, error) {
+	var changed []string
+	for _, result := range results {
+		if result.Err != nil {
+			fmt.Printf("%s: %v\n", result.Name, result.Err)
+			continue
+		}
+		fmt.Printf("%s: %s\n", result.Name, verb)
+		changed = append(changed, result.Name)
+	}
+
+	if failed := len(results) - len(changed); failed > 0 {
+		return changed, fmt.Errorf("Failed to change %d of %d links", failed, len(results))
+	}
+	return changed, nil
+}
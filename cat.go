@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/haboustak/linkctl/internal/networkd"
+)
+
+var cmdCat = &Command{
+	Name: "cat",
+	Run:  cat,
+	Usage: `Usage:
+    linkctl [-h] cat LINK
+
+Print the effective configuration of a netdev link, merging its unit with
+drop-ins from /etc, /run and /usr/lib/systemd/network. Each setting is
+annotated with the file it came from.
+
+Arguments:
+    LINK        name of the link
+
+Options:
+    -h          show this help
+`,
+}
+
+func cat(self *Command) error {
+	args := self.Flags.Args()
+
+	if len(args) != 1 {
+		return fmt.Errorf("You must provide the name of the link to print")
+	}
+
+	netdev, ok := networkd.GetNetDev(args[0])
+	if !ok {
+		return fmt.Errorf("No link with the name %s", args[0])
+	}
+
+	effective, err := netdev.Unit.Effective()
+	if err != nil {
+		return err
+	}
+
+	for _, path := range effective.Files {
+		if path == netdev.Unit.Path && netdev.SourcePath() != path {
+			fmt.Printf("# %s -> %s\n", networkd.SystemPath(path), networkd.SystemPath(netdev.SourcePath()))
+			continue
+		}
+		fmt.Printf("# %s\n", networkd.SystemPath(path))
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 4, ' ', 0)
+	for _, section := range effective.Sections {
+		fmt.Fprintf(w, "\n[%s]\n", section.Name)
+		for _, entry := range section.Entries {
+			fmt.Fprintf(w, "%s=%s\t# %s\n", entry.Key, entry.Value, networkd.SystemPath(entry.Source))
+		}
+	}
+
+	return w.Flush()
+}
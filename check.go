@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/haboustak/linkctl/internal/networkd"
+)
+
+var cmdCheck = &Command{
+	Name: "check",
+	Run:  check,
+	Usage: `Usage:
+    linkctl [-h] check [-o FORMAT]
+
+Report the files that prevent links from being discovered as they are
+configured: units that cannot be parsed, units whose name does not follow
+PREFIX-PARENT.NAME.netdev, links whose parent interface does not exist,
+and links defined twice in the same directory.
+
+Exits with status 1 if a problem is found.
+
+Options:
+    -h          show this help
+    -o FORMAT   output format: table, json or yaml (default table)
+`,
+}
+
+var checkFormat string
+
+func init() {
+	cmdCheck.Flags.StringVar(&checkFormat, "o", FormatTable, "output format")
+}
+
+func check(self *Command) error {
+	if err := validateFormat(checkFormat); err != nil {
+		return err
+	}
+
+	if err := networkd.LoadNetDevs(); err != nil {
+		return err
+	}
+	problems := networkd.Problems()
+
+	if checkFormat != FormatTable {
+		summaries := []ProblemSummary{}
+		for _, problem := range problems {
+			summaries = append(summaries, ProblemSummary{
+				Path:    networkd.SystemPath(problem.Path),
+				Link:    problem.Link,
+				Message: problem.Message,
+			})
+		}
+		if err := writeFormatted(os.Stdout, checkFormat, summaries); err != nil {
+			return err
+		}
+	} else {
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+	}
+
+	if len(problems) > 0 {
+		return exitStatus(1)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/haboustak/linkctl/internal/networkd"
+)
+
+var createConfig networkd.NetDevConfig
+var createEnable bool
+var createWait waitFlag
+var vlanId string
+
+var cmdCreate = &Command{
+	Name: "create",
+	Run:  create,
+	Usage: `Usage:
+    linkctl [-h] create KIND -parent IFACE [options]
+
+Create a netdev link definition in /etc/linkctl/user
+
+Arguments:
+    KIND                kind of link to create: vlan
+
+Options:
+    -description TEXT   description of the link
+    -enable             enable the link once it is created
+    -h                  show this help
+    -name NAME          name of the link, defaults to IFACE.ID for vlans
+    -parent IFACE       interface the link is attached to
+    -prefix PREFIX      prefix used to order the unit file (default 50)
+    -wait[=TIMEOUT]     with -enable, wait until systemd-networkd has
+                        configured the link, failing after TIMEOUT
+                        (default 30s)
+
+VLAN options:
+    -id ID              VLAN id between 1 and 4094
+`,
+}
+
+func init() {
+	cmdCreate.Flags.StringVar(&createConfig.Description, "description", "", "description of the link")
+	cmdCreate.Flags.BoolVar(&createEnable, "enable", false, "enable the link once it is created")
+	cmdCreate.Flags.StringVar(&createConfig.Name, "name", "", "name of the link")
+	cmdCreate.Flags.StringVar(&createConfig.Parent, "parent", "", "interface the link is attached to")
+	cmdCreate.Flags.StringVar(&createConfig.Prefix, "prefix", networkd.DefaultUnitPrefix, "prefix used to order the unit file")
+	cmdCreate.Flags.StringVar(&vlanId, "id", "", "VLAN id")
+	cmdCreate.Flags.Var(&createWait, "wait", "wait until the link is configured")
+}
+
+func create(self *Command) error {
+	args := self.Flags.Args()
+
+	if len(args) < 1 {
+		return errors.New("You must provide the kind of link to create")
+	}
+	createConfig.Kind = args[0]
+
+	// Options may follow the kind, e.g. create vlan -parent eth0 -id 300
+	if err := self.Flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if self.Flags.NArg() > 0 {
+		return fmt.Errorf("Unexpected argument %s", self.Flags.Arg(0))
+	}
+
+	createConfig.Settings = make(map[string]string)
+	if vlanId != "" {
+		createConfig.Settings["Id"] = vlanId
+	}
+
+	if !createEnable {
+		_, err := networkd.CreateNetDev(&createConfig)
+		return err
+	}
+
+	err := networkd.Apply(func() error {
+		netdev, err := networkd.CreateNetDev(&createConfig)
+		if err != nil {
+			return err
+		}
+		return netdev.Enable()
+	})
+	if err != nil {
+		return err
+	}
+
+	return waitForLinks(&createWait, createConfig.Name)
+}
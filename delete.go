@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/haboustak/linkctl/internal/networkd"
+)
+
+var forceDelete bool
+
+var cmdDelete = &Command{
+	Name: "delete",
+	Run:  deleteLink,
+	Usage: `Usage:
+    linkctl [-h] delete [-f] LINK
+
+Delete a netdev link definition, disabling it first if needed
+
+Arguments:
+    LINK    name of the link to delete
+
+Options:
+    -f      delete user-defined links
+    -h      show this help
+`,
+}
+
+func init() {
+	cmdDelete.Flags.BoolVar(&forceDelete, "f", false, "delete user-defined links")
+}
+
+func deleteLink(self *Command) error {
+	args := self.Flags.Args()
+
+	if len(args) != 1 {
+		return fmt.Errorf("You must provide the name of the link to delete")
+	}
+
+	netdev, ok := networkd.GetNetDev(args[0])
+	if !ok {
+		return fmt.Errorf("No link with the name %s", args[0])
+	}
+
+	// Only links networkd knows about need a restart
+	if netdev.Status == networkd.LinkDisabled {
+		tx := networkd.Begin()
+		if err := netdev.Delete(forceDelete); err != nil {
+			return tx.Rollback(err)
+		}
+		tx.Commit()
+		return nil
+	}
+
+	return networkd.Apply(func() error {
+		return netdev.Delete(forceDelete)
+	})
+}
@@ -10,40 +10,56 @@ var cmdDisable = &Command{
 	Name: "disable",
 	Run:  disable,
 	Usage: `Usage:
-    linkctl [-h] disable LINK
+    linkctl [-h] disable [-runtime] LINK...
 
-Disable a netdev link
+Disable netdev links, reloading systemd-networkd once for all of them
 
 Arguments:
-    LINK    name of the link to disable
+    LINK    name of a link to disable, or a glob pattern matching the
+            enabled links to disable, e.g. 'test.3*'
 
 Options:
     -h      show this help
+    -runtime
+            disable a link enabled with enable -runtime
 `,
 }
 
+var disableRuntime bool
+
 func init() {
+	cmdDisable.Flags.BoolVar(&disableRuntime, "runtime", false, "disable a link enabled until the next reboot")
 }
 
 func disable(self *Command) error {
 	args := self.Flags.Args()
 
-	if len(args) != 1 {
+	if len(args) < 1 {
 		return fmt.Errorf("You must provide the name of the unit to disable")
 	}
 
-	netdev, ok := networkd.GetNetDev(args[0])
-	if !ok {
-		return fmt.Errorf("No netdev with the name %s", args[0])
+	status := networkd.LinkStatus(networkd.LinkEnabled)
+	if disableRuntime {
+		status = networkd.LinkEnabledRuntime
 	}
 
-	if err := netdev.Disable(); err != nil {
+	links, err := resolveLinks(args, func(netdev *networkd.NetDev) bool {
+		return netdev.Status == status
+	})
+	if err != nil {
 		return err
 	}
 
-	if err := networkd.Restart(); err != nil {
-		return fmt.Errorf("Failed to restart systemd-networkd: %s", err)
+	results, err := networkd.ApplyEach(links, func(netdev *networkd.NetDev) error {
+		if disableRuntime {
+			return netdev.DisableRuntime()
+		}
+		return netdev.Disable()
+	})
+	if err != nil {
+		return err
 	}
 
-	return nil
+	_, err = printResults(results, "disabled")
+	return err
 }
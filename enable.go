@@ -10,37 +10,60 @@ var cmdEnable = &Command{
 	Name: "enable",
 	Run:  enable,
 	Usage: `Usage:
-    linkctl [-h] enable LINK
+    linkctl [-h] enable [-runtime] [-wait[=TIMEOUT]] LINK...
 
-Enable a netdev link
+Enable netdev links, reloading systemd-networkd once for all of them
 
 Arguments:
-    LINK    name of the link to enable
+    LINK    name of a link to enable, or a glob pattern matching the
+            disabled links to enable, e.g. 'test.3*'
 
 Options:
     -h      show this help
+    -runtime
+            enable the link until the next reboot by linking it into
+            /run/systemd/network instead of /etc/systemd/network
+    -wait[=TIMEOUT]
+            wait until systemd-networkd has configured the link, failing
+            after TIMEOUT (default 30s)
 `,
 }
 
+var enableWait waitFlag
+var enableRuntime bool
+
+func init() {
+	cmdEnable.Flags.BoolVar(&enableRuntime, "runtime", false, "enable the link until the next reboot")
+	cmdEnable.Flags.Var(&enableWait, "wait", "wait until the link is configured")
+}
+
 func enable(self *Command) error {
 	args := self.Flags.Args()
 
-	if len(args) != 1 {
+	if len(args) < 1 {
 		return fmt.Errorf("You must provide the name of the link to enable")
 	}
 
-	netdev, ok := networkd.GetNetDev(args[0])
-	if !ok {
-		return fmt.Errorf("No link with the name %s", args[0])
+	links, err := resolveLinks(args, func(netdev *networkd.NetDev) bool {
+		return netdev.Status == networkd.LinkDisabled
+	})
+	if err != nil {
+		return err
 	}
 
-	if err := netdev.Enable(); err != nil {
+	results, err := networkd.ApplyEach(links, func(netdev *networkd.NetDev) error {
+		if enableRuntime {
+			return netdev.EnableRuntime()
+		}
+		return netdev.Enable()
+	})
+	if err != nil {
 		return err
 	}
 
-	if err := networkd.Restart(); err != nil {
-		return fmt.Errorf("Failed to restart systemd-networkd: %s", err)
+	enabled, err := printResults(results, "enabled")
+	if waitErr := waitForLinks(&enableWait, enabled...); waitErr != nil && err == nil {
+		err = waitErr
 	}
-
-	return nil
+	return err
 }
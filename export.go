@@ -0,0 +1,41 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	"github.com/haboustak/linkctl/internal/networkd"
+)
+
+var cmdExport = &Command{
+	Name: "export",
+	Run:  export,
+	Usage: `Usage:
+    linkctl [-h] export [-o FORMAT]
+
+Print a manifest of every netdev link with its unit, effective name,
+status and description. The manifest can be given to apply to bring
+another host to the same state.
+
+Options:
+    -h          show this help
+    -o FORMAT   output format: json or yaml (default yaml)
+`,
+}
+
+var exportFormat string
+
+func init() {
+	cmdExport.Flags.StringVar(&exportFormat, "o", FormatYAML, "output format")
+}
+
+func export(self *Command) error {
+	if err := validateFormat(exportFormat); err != nil {
+		return err
+	}
+	if exportFormat == FormatTable {
+		return errors.New("A manifest cannot be exported as a table, use json or yaml")
+	}
+
+	return writeFormatted(os.Stdout, exportFormat, newManifest(networkd.ListNetDev(true)))
+}
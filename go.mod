@@ -3,7 +3,8 @@ module github.com/haboustak/linkctl
 go 1.18
 
 require (
-	github.com/smartystreets/goconvey v1.7.2 // indirect
-	golang.org/x/sys v0.0.0-20200625212154-ddb9806d33ae
-	gopkg.in/ini.v1 v1.57.0
+	github.com/godbus/dbus/v5 v5.1.0
+	golang.org/x/crypto v0.8.0
+	golang.org/x/sys v0.7.0
+	gopkg.in/yaml.v3 v3.0.1
 )
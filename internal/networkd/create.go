@@ -0,0 +1,150 @@
+package networkd
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const DefaultUnitPrefix = "50"
+
+// NetDevConfig describes a new netdev definition
+type NetDevConfig struct {
+	Kind        string
+	Name        string
+	Parent      string
+	Description string
+	// Prefix orders the unit among the other networkd units
+	Prefix string
+	// Settings are written to the kind-specific section, e.g. [VLAN]
+	Settings map[string]string
+}
+
+func (self *NetDevConfig) unitName() string {
+	if isStandalone(self.Kind) {
+		return fmt.Sprintf("%s-%s.netdev", self.Prefix, self.Name)
+	}
+	return fmt.Sprintf("%s-%s.%s.netdev", self.Prefix, self.Parent, self.Name)
+}
+
+func (self *NetDevConfig) validate() error {
+	kind, ok := kinds[self.Kind]
+	if !ok || kind.validate == nil {
+		return fmt.Errorf("Unable to create links of kind %s, expected one of %s",
+			self.Kind, strings.Join(Kinds(), ", "))
+	}
+
+	if isStandalone(self.Kind) {
+		if self.Parent != "" {
+			return fmt.Errorf("Links of kind %s do not have a parent interface", self.Kind)
+		}
+	} else if err := self.validateParent(); err != nil {
+		return err
+	}
+
+	if self.Prefix == "" {
+		self.Prefix = DefaultUnitPrefix
+	}
+	if strings.Contains(self.Prefix, "-") {
+		return fmt.Errorf("The unit prefix %s may not contain '-'", self.Prefix)
+	}
+
+	if err := kind.validate(self.Settings); err != nil {
+		return err
+	}
+
+	if self.Name == "" && kind.defaultName != nil {
+		self.Name = kind.defaultName(self)
+	}
+
+	return ValidateLinkName(self.Name)
+}
+
+func (self *NetDevConfig) validateParent() error {
+	if self.Parent == "" {
+		return fmt.Errorf("A parent interface is required for %s links", self.Kind)
+	}
+	if err := ValidateLinkName(self.Parent); err != nil {
+		return err
+	}
+	if strings.Contains(self.Parent, ".") {
+		return fmt.Errorf("The parent interface %s may not contain '.'", self.Parent)
+	}
+
+	return nil
+}
+
+func (self *NetDevConfig) newUnit(path string) *Unit {
+	unit := Unit{
+		Path: path,
+		Name: filepath.Base(path),
+		File: NewUnitFile(),
+	}
+
+	section := unit.File.Section("NetDev")
+	section.Set("Name", self.Name)
+	section.Set("Kind", self.Kind)
+	if self.Description != "" {
+		section.Set("Description", self.Description)
+	}
+
+	var keys []string
+	for key := range self.Settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	section = unit.File.Section(kinds[self.Kind].Section)
+	for _, key := range keys {
+		section.Set(key, self.Settings[key])
+	}
+
+	return &unit
+}
+
+// CreateNetDev writes a new netdev definition to the user directory and
+// adds it to the default inventory. The netdev is available to be enabled
+// but is not enabled.
+func CreateNetDev(config *NetDevConfig) (*NetDev, error) {
+	return defaultInventory.CreateNetDev(config)
+}
+
+// CreateNetDev writes a new netdev definition to the user directory and
+// adds it to the inventory. The netdev is available to be enabled but is
+// not enabled.
+func (self *Inventory) CreateNetDev(config *NetDevConfig) (*NetDev, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	if _, ok := self.Get(config.Name); ok {
+		return nil, fmt.Errorf("A link with the name %s already exists", config.Name)
+	}
+
+	if _, err := net.InterfaceByName(config.Name); err == nil {
+		return nil, fmt.Errorf("A link with the name %s already exists", config.Name)
+	}
+
+	unitName := config.unitName()
+	for _, dir := range []string{options.NetworkDir, options.UserDir, options.SystemDir, options.AvailableDir} {
+		path := filepath.Join(options.Path(dir), unitName)
+		if fileExists(path) {
+			return nil, fmt.Errorf("The unit %s already exists", path)
+		}
+	}
+
+	unit := config.newUnit(filepath.Join(options.Path(options.UserDir), unitName))
+	if err := unit.Save(); err != nil {
+		return nil, fmt.Errorf("Failed to save unit %s: %w", unit.Path, err)
+	}
+
+	netdev, err := self.newNetDev(unit.Path, AvailableLink)
+	if err != nil {
+		return nil, err
+	}
+	self.add(netdev)
+
+	return netdev, nil
+}
@@ -0,0 +1,162 @@
+package networkd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	networkBusName   = "org.freedesktop.network1"
+	networkPath      = "/org/freedesktop/network1"
+	managerInterface = "org.freedesktop.network1.Manager"
+	linkInterface    = "org.freedesktop.network1.Link"
+)
+
+// LinkState is the state systemd-networkd reports for a link
+type LinkState struct {
+	OperationalState    string
+	AdministrativeState string
+	CarrierState        string
+	AddressState        string
+}
+
+// errNoBus indicates systemd-networkd cannot be reached over D-Bus
+var errNoBus = errors.New("systemd-networkd is not available on the system bus")
+
+func isNoBus(err error) bool {
+	var dbusErr dbus.Error
+	if errors.As(err, &dbusErr) {
+		return dbusErr.Name == "org.freedesktop.DBus.Error.ServiceUnknown" ||
+			dbusErr.Name == "org.freedesktop.DBus.Error.NameHasNoOwner"
+	}
+	return errors.Is(err, errNoBus)
+}
+
+func connectNetworkd(ctx context.Context) (*dbus.Conn, dbus.BusObject, error) {
+	conn, err := dbus.ConnectSystemBus(dbus.WithContext(ctx))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", errNoBus, err)
+	}
+
+	return conn, conn.Object(networkBusName, networkPath), nil
+}
+
+func reloadDBus(ctx context.Context, links []string) error {
+	conn, manager, err := connectNetworkd(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := manager.CallWithContext(ctx, managerInterface+".Reload", 0).Err; err != nil {
+		return err
+	}
+
+	for _, link := range links {
+		var index int32
+		var path dbus.ObjectPath
+
+		// Links that do not exist yet are configured when networkd creates them
+		call := manager.CallWithContext(ctx, managerInterface+".GetLinkByName", 0, link)
+		if call.Store(&index, &path) != nil {
+			continue
+		}
+
+		call = manager.CallWithContext(ctx, managerInterface+".ReconfigureLink", 0, index)
+		if call.Err != nil {
+			return fmt.Errorf("Failed to reconfigure link %s: %w", link, call.Err)
+		}
+	}
+
+	return nil
+}
+
+func reloadNetworkctl(ctx context.Context, links []string) error {
+	if out, err := exec.CommandContext(ctx, "networkctl", "reload").CombinedOutput(); err != nil {
+		return fmt.Errorf("networkctl reload: %w: %s", err, out)
+	}
+
+	var existing []string
+	for _, link := range links {
+		intf, err := NewInterface(link)
+		if err != nil {
+			return err
+		}
+		if intf.NetIf != nil {
+			existing = append(existing, link)
+		}
+	}
+	if len(existing) == 0 {
+		return nil
+	}
+
+	args := append([]string{"reconfigure"}, existing...)
+	if out, err := exec.CommandContext(ctx, "networkctl", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("networkctl reconfigure: %w: %s", err, out)
+	}
+
+	return nil
+}
+
+// GetLinkState asks systemd-networkd for the state of a link, falling back
+// to the state files it writes when D-Bus is unavailable.
+func GetLinkState(ctx context.Context, name string) (*LinkState, error) {
+	state, err := linkStateDBus(ctx, name)
+	if err == nil || !isNoBus(err) {
+		return state, err
+	}
+
+	intf, err := NewInterface(name)
+	if err != nil {
+		return nil, err
+	}
+	if intf.NetIf == nil {
+		return nil, fmt.Errorf("There is no interface \"%s\"", name)
+	}
+
+	values, err := readLinkState(intf.NetIf.Index)
+	if err != nil {
+		return nil, err
+	}
+
+	return newLinkState(values), nil
+}
+
+func linkStateDBus(ctx context.Context, name string) (*LinkState, error) {
+	conn, manager, err := connectNetworkd(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var index int32
+	var path dbus.ObjectPath
+	call := manager.CallWithContext(ctx, managerInterface+".GetLinkByName", 0, name)
+	if err := call.Store(&index, &path); err != nil {
+		return nil, err
+	}
+
+	link := conn.Object(networkBusName, path)
+	var state LinkState
+	properties := map[string]*string{
+		"OperationalState":    &state.OperationalState,
+		"AdministrativeState": &state.AdministrativeState,
+		"CarrierState":        &state.CarrierState,
+		"AddressState":        &state.AddressState,
+	}
+	for property, value := range properties {
+		variant, err := link.GetProperty(linkInterface + "." + property)
+		if err != nil {
+			return nil, err
+		}
+		if err := variant.Store(value); err != nil {
+			return nil, err
+		}
+	}
+
+	return &state, nil
+}
@@ -0,0 +1,166 @@
+package networkd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const diffContext = 3
+
+type diffLine struct {
+	op   byte
+	text string
+}
+
+func splitLines(data []byte) []string {
+	text := string(data)
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes a line-based edit script from a to b using the
+// longest common subsequence. Unit files are small enough that the
+// quadratic table is not a concern.
+func diffLines(a []string, b []string) []diffLine {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, diffLine{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, diffLine{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, diffLine{'+', b[j]})
+	}
+
+	return lines
+}
+
+func hunkRange(start int, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// unifiedDiff writes the differences between two files in unified format.
+// Nothing is written if the files are identical.
+func unifiedDiff(w io.Writer, fromName string, toName string, from []byte, to []byte) error {
+	lines := diffLines(splitLines(from), splitLines(to))
+
+	changed := false
+	for _, line := range lines {
+		if line.op != ' ' {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	if _, err := fmt.Fprintf(w, "--- %s\n+++ %s\n", fromName, toName); err != nil {
+		return err
+	}
+
+	// Track the position of every line in the original and updated file
+	aPos := make([]int, len(lines)+1)
+	bPos := make([]int, len(lines)+1)
+	for n, line := range lines {
+		aPos[n+1], bPos[n+1] = aPos[n], bPos[n]
+		if line.op != '+' {
+			aPos[n+1]++
+		}
+		if line.op != '-' {
+			bPos[n+1]++
+		}
+	}
+
+	for n := 0; n < len(lines); {
+		if lines[n].op == ' ' {
+			n++
+			continue
+		}
+
+		start := n - diffContext
+		if start < 0 {
+			start = 0
+		}
+
+		// Extend the hunk until there is enough unchanged context to close it
+		end := n
+		for end < len(lines) {
+			if lines[end].op != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(lines) && lines[run].op == ' ' {
+				run++
+			}
+			if run == len(lines) || run-end > 2*diffContext {
+				end += diffContext
+				if end > len(lines) {
+					end = len(lines)
+				}
+				break
+			}
+			end = run
+		}
+
+		fmt.Fprintf(w, "@@ -%s +%s @@\n",
+			hunkRange(aPos[start], aPos[end]-aPos[start]),
+			hunkRange(bPos[start], bPos[end]-bPos[start]))
+		for _, line := range lines[start:end] {
+			text := line.text
+			if !strings.HasSuffix(text, "\n") {
+				text += "\n\\ No newline at end of file\n"
+			}
+			if _, err := fmt.Fprintf(w, "%c%s", line.op, text); err != nil {
+				return err
+			}
+		}
+
+		n = end
+	}
+
+	return nil
+}
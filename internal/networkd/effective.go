@@ -0,0 +1,190 @@
+package networkd
+
+// Sections that define one object each time they appear, e.g. an address
+// or a peer, rather than being merged with earlier sections of the same
+// name.
+var repeatedSections = map[string]bool{
+	"Address":               true,
+	"BridgeFDB":             true,
+	"BridgeMDB":             true,
+	"BridgeVLAN":            true,
+	"DHCPServerStaticLease": true,
+	"IPv6AddressLabel":      true,
+	"IPv6Prefix":            true,
+	"IPv6RoutePrefix":       true,
+	"Neighbor":              true,
+	"NextHop":               true,
+	"Route":                 true,
+	"RoutingPolicyRule":     true,
+	"SR-IOV":                true,
+	"WireGuardPeer":         true,
+}
+
+// Settings that accumulate a list when assigned more than once, every
+// other setting is overridden by a later assignment.
+var listSettings = map[string]map[string]bool{
+	"Match": {
+		"Driver":              true,
+		"Host":                true,
+		"KernelCommandLine":   true,
+		"KernelVersion":       true,
+		"MACAddress":          true,
+		"Name":                true,
+		"OriginalName":        true,
+		"Path":                true,
+		"PermanentMACAddress": true,
+		"Property":            true,
+		"Type":                true,
+	},
+	"Network": {
+		"Address":     true,
+		"BindCarrier": true,
+		"DNS":         true,
+		"Domains":     true,
+		"Gateway":     true,
+		"IPVLAN":      true,
+		"IPVTAP":      true,
+		"MACVLAN":     true,
+		"MACVTAP":     true,
+		"MACsec":      true,
+		"NTP":         true,
+		"Tunnel":      true,
+		"VLAN":        true,
+		"VXLAN":       true,
+		"Xfrm":        true,
+	},
+	"WireGuardPeer": {
+		"AllowedIPs": true,
+	},
+}
+
+// EffectiveUnit is the configuration systemd-networkd applies for a unit
+// once its drop-ins have been merged.
+type EffectiveUnit struct {
+	// Files are the unit and its drop-ins in the order they are applied
+	Files    []string
+	Sections []*EffectiveSection
+}
+
+type EffectiveSection struct {
+	Name    string
+	Entries []*EffectiveEntry
+}
+
+// EffectiveEntry is an assignment along with the file it came from
+type EffectiveEntry struct {
+	Key    string
+	Value  string
+	Source string
+}
+
+// Effective merges the unit with its drop-ins. Later files override
+// settings of earlier ones, extend list settings, and an empty assignment
+// resets a setting.
+func (self *Unit) Effective() (*EffectiveUnit, error) {
+	dropins, err := self.DropinUnits()
+	if err != nil {
+		return nil, err
+	}
+
+	return newEffectiveUnit(append([]*Unit{self}, dropins...)), nil
+}
+
+func newEffectiveUnit(units []*Unit) *EffectiveUnit {
+	var effective EffectiveUnit
+	for _, unit := range units {
+		effective.Files = append(effective.Files, unit.Path)
+		effective.merge(unit)
+	}
+	return &effective
+}
+
+func (self *EffectiveUnit) merge(unit *Unit) {
+	for _, section := range unit.File.Sections() {
+		var target *EffectiveSection
+		if !repeatedSections[section.Name] {
+			target = self.Section(section.Name)
+		}
+		if target == nil {
+			target = &EffectiveSection{Name: section.Name}
+			self.Sections = append(self.Sections, target)
+		}
+
+		for _, entry := range section.entries {
+			if entry.Key != "" {
+				target.assign(entry.Key, entry.Value, unit.Path)
+			}
+		}
+	}
+}
+
+func (self *EffectiveSection) assign(key string, value string, source string) {
+	if value == "" {
+		self.remove(key)
+		return
+	}
+
+	entry := &EffectiveEntry{Key: key, Value: value, Source: source}
+	if listSettings[self.Name][key] {
+		self.Entries = append(self.Entries, entry)
+		return
+	}
+
+	for i, existing := range self.Entries {
+		if existing.Key == key {
+			self.Entries[i] = entry
+			return
+		}
+	}
+	self.Entries = append(self.Entries, entry)
+}
+
+func (self *EffectiveSection) remove(key string) {
+	kept := self.Entries[:0]
+	for _, entry := range self.Entries {
+		if entry.Key != key {
+			kept = append(kept, entry)
+		}
+	}
+	self.Entries = kept
+}
+
+// Section returns the merged section with the given name, or nil. Repeated
+// sections such as [Address] are not merged, only the first is returned.
+func (self *EffectiveUnit) Section(name string) *EffectiveSection {
+	for _, section := range self.Sections {
+		if section.Name == name {
+			return section
+		}
+	}
+	return nil
+}
+
+// Get returns the effective assignment of key, or nil if it is not set
+func (self *EffectiveUnit) Get(section string, key string) *EffectiveEntry {
+	s := self.Section(section)
+	if s == nil {
+		return nil
+	}
+
+	var found *EffectiveEntry
+	for _, entry := range s.Entries {
+		if entry.Key == key {
+			found = entry
+		}
+	}
+	return found
+}
+
+// Values returns the words of a list setting across every assignment
+func (self *EffectiveUnit) Values(section string, key string) []string {
+	values := []string{}
+	if s := self.Section(section); s != nil {
+		for _, entry := range s.Entries {
+			if entry.Key == key {
+				values = append(values, splitWords(entry.Value)...)
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,376 @@
+package networkd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Inventory is the set of netdevs discovered in the networkd and linkctl
+// directories, along with the networks of the interfaces they refer to.
+// Netdevs are indexed by link name and by unit, and the indexes follow
+// netdevs that are created, renamed or deleted. An inventory is loaded on
+// first use and again once Configure is called or a transaction is rolled
+// back, Refresh picks up changes made by others.
+type Inventory struct {
+	byName   map[string]*NetDev
+	byUnit   map[string]*NetDev
+	networks map[string]*Network
+	problems []Problem
+
+	// generation is the value of configGeneration the inventory was loaded
+	// with
+	generation int
+	loaded     bool
+}
+
+// Problem is a file that prevented a link from being discovered, or that
+// defines a link systemd-networkd cannot create as configured
+type Problem struct {
+	// Path is the file responsible
+	Path    string
+	Link    string
+	Message string
+}
+
+func (self Problem) String() string {
+	return fmt.Sprintf("%s: %s", SystemPath(self.Path), self.Message)
+}
+
+// newProblem describes err, blaming the unit or file named by err when
+// there is one rather than path
+func newProblem(path string, link string, err error) Problem {
+	var unitErr *UnitError
+	var pathErr *os.PathError
+	switch {
+	case errors.As(err, &unitErr):
+		return Problem{Path: unitErr.Path, Link: link, Message: unitErr.Err.Error()}
+	case errors.As(err, &pathErr):
+		return Problem{Path: pathErr.Path, Link: link, Message: pathErr.Err.Error()}
+	}
+	return Problem{Path: path, Link: link, Message: err.Error()}
+}
+
+// configGeneration changes whenever every inventory must be reloaded
+var configGeneration int
+
+// defaultInventory backs the package-level functions used by the linkctl
+// command
+var defaultInventory = NewInventory()
+
+// NewInventory returns an inventory that is loaded on first use
+func NewInventory() *Inventory {
+	return &Inventory{}
+}
+
+// invalidateInventories discards what every inventory has loaded
+func invalidateInventories() {
+	configGeneration++
+}
+
+func (self *Inventory) reset() {
+	self.byName = make(map[string]*NetDev)
+	self.byUnit = make(map[string]*NetDev)
+	self.networks = make(map[string]*Network)
+	self.problems = nil
+	self.generation = configGeneration
+	self.loaded = false
+}
+
+// Load discovers netdevs unless the inventory is already loaded and
+// current
+func (self *Inventory) Load() error {
+	if self.loaded && self.generation == configGeneration {
+		return nil
+	}
+	return self.Refresh()
+}
+
+// Refresh discards every netdev and network and discovers them again.
+//
+// Netdevs are discovered the way systemd-networkd does: a unit in
+// NetworkDir shadows a unit with the same file name in RuntimeDir, which
+// shadows one in VendorDir. A unit shadowed by a mask is reported as
+// masked. Netdevs available to be enabled by linkctl are loaded last, from
+// UserDir, SystemDir and AvailableDir.
+//
+// When several units define a link with the same name the first one found
+// in that order wins, so an enabled link overrides the available
+// definitions and a user definition overrides a system one. Within a
+// directory units are read in file name order.
+func (self *Inventory) Refresh() error {
+	self.reset()
+	// A failed discovery is not retried until the next refresh
+	self.loaded = true
+
+	linkTypes := map[string]LinkType{
+		options.NetworkDir: EnabledLink,
+		options.RuntimeDir: RuntimeLink,
+		options.VendorDir:  VendorLink,
+	}
+
+	found := make(map[string]bool)
+	masked := make(map[string]bool)
+	for _, dir := range options.searchDirs() {
+		files, err := globNetDevs(dir)
+		if err != nil {
+			return self.failed(dir, err)
+		}
+		for _, file := range files {
+			name := filepath.Base(file)
+			switch {
+			case masked[name]:
+				// The first unit beneath a mask is the one it hides
+				masked[name] = false
+				self.load(file, MaskedLink)
+			case found[name]:
+			case isMasked(file):
+				masked[name] = true
+			default:
+				self.load(file, linkTypes[dir])
+			}
+			found[name] = true
+		}
+	}
+
+	for _, dir := range []string{options.UserDir, options.SystemDir, options.AvailableDir} {
+		files, err := globNetDevs(dir)
+		if err != nil {
+			return self.failed(dir, err)
+		}
+		for _, file := range files {
+			if masked[filepath.Base(file)] {
+				self.load(file, MaskedLink)
+				continue
+			}
+			self.load(file, AvailableLink)
+		}
+	}
+
+	return nil
+}
+
+// failed records a discovery error as a problem, so List and Problems,
+// which do not return errors, report it rather than an empty inventory
+func (self *Inventory) failed(dir string, err error) error {
+	self.problems = append(self.problems, newProblem(options.Path(dir), "", err))
+	return err
+}
+
+func globNetDevs(dir string) ([]string, error) {
+	path := filepath.Join(options.Path(dir), "*.netdev")
+	files, err := glob(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list units at %s: %w", path, err)
+	}
+	return files, nil
+}
+
+// load adds the netdev defined by file unless a netdev with the same name
+// has already been found, in which case file is recorded as shadowed by
+// that netdev. Units that fail to load are recorded as problems.
+func (self *Inventory) load(file string, linkType LinkType) {
+	netdev, err := self.newNetDev(file, linkType)
+	if err != nil {
+		self.problems = append(self.problems, newProblem(file, "", err))
+		return
+	}
+
+	if existing, exist := self.byName[netdev.Name]; exist {
+		// An enabled link is found again where its symlink points
+		if filepath.Clean(existing.SourcePath()) == filepath.Clean(file) {
+			return
+		}
+
+		// A directory of higher precedence overrides the definition, but
+		// within a directory the winner only depends on the file names
+		existing.Shadowed = append(existing.Shadowed, file)
+		if filepath.Dir(existing.Unit.Path) == filepath.Dir(file) {
+			self.problems = append(self.problems, Problem{
+				Path:    file,
+				Link:    netdev.Name,
+				Message: fmt.Sprintf("The link %s is already defined by %s in the same directory", netdev.Name, SystemPath(existing.Unit.Path)),
+			})
+		}
+		return
+	}
+
+	self.add(netdev)
+	self.check(netdev)
+}
+
+// check records a problem if systemd-networkd cannot attach the netdev to
+// its parent interface
+func (self *Inventory) check(netdev *NetDev) {
+	if isStandalone(netdev.Kind) || netdev.Status == LinkMasked {
+		return
+	}
+
+	intfName, err := netdev.parseUnitName()
+	if err != nil || netdev.ParentNetwork == nil {
+		self.problems = append(self.problems, Problem{
+			Path:    netdev.Unit.Path,
+			Link:    netdev.Name,
+			Message: fmt.Sprintf("The unit name does not follow PREFIX-PARENT.NAME.netdev, the parent interface of %s is unknown", netdev.Name),
+		})
+		return
+	}
+
+	if !netdev.ParentNetwork.Interface.present() {
+		self.problems = append(self.problems, Problem{
+			Path:    netdev.Unit.Path,
+			Link:    netdev.Name,
+			Message: fmt.Sprintf("The parent interface %s of %s does not exist", intfName, netdev.Name),
+		})
+	}
+}
+
+// Problems returns what prevented links from being discovered as they are
+// configured, in the order they were found
+func (self *Inventory) Problems() []Problem {
+	self.Load()
+	return self.problems
+}
+
+func (self *Inventory) add(netdev *NetDev) {
+	netdev.inventory = self
+	if _, exist := self.byName[netdev.Name]; !exist {
+		self.byName[netdev.Name] = netdev
+	}
+	if _, exist := self.byUnit[netdev.Unit.Name]; !exist {
+		self.byUnit[netdev.Unit.Name] = netdev
+	}
+}
+
+func (self *Inventory) remove(netdev *NetDev) {
+	if self.byName[netdev.Name] == netdev {
+		delete(self.byName, netdev.Name)
+	}
+	if self.byUnit[netdev.Unit.Name] == netdev {
+		delete(self.byUnit, netdev.Unit.Name)
+	}
+}
+
+// rename moves a netdev to the name it was given by a drop-in
+func (self *Inventory) rename(netdev *NetDev, oldName string) {
+	if oldName == netdev.Name || self.byName[oldName] != netdev {
+		return
+	}
+	delete(self.byName, oldName)
+	self.byName[netdev.Name] = netdev
+}
+
+// List returns the netdevs sorted by name, only enabled netdevs unless
+// listAll is set. Netdevs that fail to load are left out.
+func (self *Inventory) List(listAll bool) []*NetDev {
+	self.Load()
+
+	var keys []string
+	for name, netdev := range self.byName {
+		if listAll || netdev.Status != LinkDisabled {
+			keys = append(keys, name)
+		}
+	}
+	sort.Strings(keys)
+
+	var values []*NetDev
+	for _, name := range keys {
+		values = append(values, self.byName[name])
+	}
+	return values
+}
+
+// Get returns the netdev with the given link name
+func (self *Inventory) Get(name string) (*NetDev, bool) {
+	self.Load()
+
+	netdev, ok := self.byName[name]
+	return netdev, ok
+}
+
+// GetByUnit returns the netdev defined by the unit with the given file
+// name, e.g. 50-eth0.test.300.netdev
+func (self *Inventory) GetByUnit(unitName string) (*NetDev, bool) {
+	self.Load()
+
+	netdev, ok := self.byUnit[unitName]
+	return netdev, ok
+}
+
+// Network returns the network of an interface: the interface itself and
+// the network unit systemd-networkd configured it with. It returns nil
+// for an empty name.
+func (self *Inventory) Network(intfName string) *Network {
+	if intfName == "" {
+		return nil
+	}
+
+	if self.networks == nil || self.generation != configGeneration {
+		// Networks are looked up while netdevs load, so only reset them
+		// when the inventory is stale
+		self.reset()
+	}
+
+	if network, ok := self.networks[intfName]; ok {
+		return network
+	}
+
+	var network Network
+	intf, err := NewInterface(intfName)
+	if err != nil {
+		self.problems = append(self.problems, newProblem("", intfName, err))
+	}
+	network.Interface = intf
+	switch {
+	case !options.IsLive():
+		// The state files of the running system do not describe the root
+		unit, err := matchNetworkUnit(intfName)
+		if err != nil {
+			self.problems = append(self.problems, newProblem("", intfName, err))
+		}
+		network.Unit = unit
+	case network.Interface.NetIf != nil:
+		unit, err := getNetworkUnit(network.Interface)
+		// Interfaces systemd-networkd does not manage have no state file
+		if err != nil && !os.IsNotExist(err) {
+			self.problems = append(self.problems, newProblem("", intfName, err))
+		}
+		network.Unit = unit
+	}
+
+	self.networks[intfName] = &network
+	return &network
+}
+
+// LoadNetDevs discards the netdevs of the default inventory and discovers
+// them again
+func LoadNetDevs() error {
+	return defaultInventory.Refresh()
+}
+
+// ListNetDev returns the netdevs of the default inventory sorted by name,
+// only enabled netdevs unless listAll is set. Netdevs that fail to load
+// are left out, LoadNetDevs reports why discovery failed.
+func ListNetDev(listAll bool) []*NetDev {
+	return defaultInventory.List(listAll)
+}
+
+// GetNetDev returns the netdev with the given link name from the default
+// inventory
+func GetNetDev(linkName string) (*NetDev, bool) {
+	return defaultInventory.Get(linkName)
+}
+
+// Problems returns what prevented links of the default inventory from being
+// discovered as they are configured
+func Problems() []Problem {
+	return defaultInventory.Problems()
+}
+
+// GetNetDevByUnit returns the netdev defined by the unit with the given
+// file name from the default inventory
+func GetNetDevByUnit(unitName string) (*NetDev, bool) {
+	return defaultInventory.GetByUnit(unitName)
+}
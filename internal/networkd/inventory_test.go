@@ -0,0 +1,206 @@
+package networkd
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRefresh(t *testing.T) {
+	const (
+		network = "etc/systemd/network/10-eth0.network"
+		etc     = "etc/systemd/network/50-eth0.v300.netdev"
+		run     = "run/systemd/network/50-eth0.v300.netdev"
+		vendor  = "usr/lib/systemd/network/50-eth0.v300.netdev"
+		user    = "etc/linkctl/user/50-eth0.v300.netdev"
+		system  = "etc/linkctl/system/50-eth0.v300.netdev"
+	)
+
+	tests := []struct {
+		name         string
+		files        map[string]string
+		wantStatus   LinkStatus
+		wantUnit     string
+		wantShadowed []string
+		wantProblems []string
+	}{
+		{
+			name:       "vendor",
+			files:      map[string]string{vendor: vlanUnit("v300", "300")},
+			wantStatus: LinkVendor,
+			wantUnit:   vendor,
+		},
+		{
+			name: "runtime shadows vendor",
+			files: map[string]string{
+				run:    vlanUnit("v300", "300"),
+				vendor: vlanUnit("v300", "301"),
+			},
+			wantStatus: LinkRuntime,
+			wantUnit:   run,
+		},
+		{
+			name: "etc shadows vendor",
+			files: map[string]string{
+				etc:    vlanUnit("v300", "300"),
+				vendor: vlanUnit("v300", "301"),
+			},
+			wantStatus: LinkUserDefined,
+			wantUnit:   etc,
+		},
+		{
+			name: "masked by symlink",
+			files: map[string]string{
+				etc:    "-> /dev/null",
+				vendor: vlanUnit("v300", "300"),
+			},
+			wantStatus: LinkMasked,
+			wantUnit:   vendor,
+		},
+		{
+			name: "masked by empty file",
+			files: map[string]string{
+				run:    "",
+				vendor: vlanUnit("v300", "300"),
+			},
+			wantStatus: LinkMasked,
+			wantUnit:   vendor,
+		},
+		{
+			name: "masked available",
+			files: map[string]string{
+				etc:    "-> /dev/null",
+				system: vlanUnit("v300", "300"),
+			},
+			wantStatus: LinkMasked,
+			wantUnit:   system,
+		},
+		{
+			name: "enabled",
+			files: map[string]string{
+				etc:    "-> /" + system,
+				system: vlanUnit("v300", "300"),
+			},
+			wantStatus: LinkEnabled,
+			wantUnit:   etc,
+		},
+		{
+			name: "enabled runtime",
+			files: map[string]string{
+				run:    "-> /" + system,
+				system: vlanUnit("v300", "300"),
+			},
+			wantStatus: LinkEnabledRuntime,
+			wantUnit:   run,
+		},
+		{
+			name: "user shadows system",
+			files: map[string]string{
+				user:   vlanUnit("v300", "300"),
+				system: vlanUnit("v300", "301"),
+			},
+			wantStatus:   LinkDisabled,
+			wantUnit:     user,
+			wantShadowed: []string{system},
+		},
+		{
+			name: "same name in one directory",
+			files: map[string]string{
+				"etc/linkctl/system/50-eth0.v300.netdev": vlanUnit("v300", "300"),
+				"etc/linkctl/system/60-eth0.v300.netdev": vlanUnit("v300", "301"),
+			},
+			wantStatus:   LinkDisabled,
+			wantUnit:     "etc/linkctl/system/50-eth0.v300.netdev",
+			wantShadowed: []string{"etc/linkctl/system/60-eth0.v300.netdev"},
+			wantProblems: []string{"etc/linkctl/system/60-eth0.v300.netdev"},
+		},
+		{
+			name: "unknown parent",
+			files: map[string]string{
+				"etc/linkctl/system/v300.netdev": vlanUnit("v300", "300"),
+			},
+			wantStatus:   LinkDisabled,
+			wantUnit:     "etc/linkctl/system/v300.netdev",
+			wantProblems: []string{"etc/linkctl/system/v300.netdev"},
+		},
+		{
+			name: "invalid unit",
+			files: map[string]string{
+				"etc/linkctl/system/40-eth0.v300.netdev": "[NetDev\n",
+				system:                                   vlanUnit("v300", "300"),
+			},
+			wantStatus:   LinkDisabled,
+			wantUnit:     system,
+			wantProblems: []string{"etc/linkctl/system/40-eth0.v300.netdev"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.files[network] = "[Match]\nName=eth0\n"
+			root := newTestRoot(t, test.files)
+
+			inventory := NewInventory()
+			if err := inventory.Refresh(); err != nil {
+				t.Fatal(err)
+			}
+
+			var problems []string
+			for _, problem := range inventory.Problems() {
+				problems = append(problems, strings.TrimPrefix(problem.Path, root+"/"))
+			}
+			if !reflect.DeepEqual(problems, test.wantProblems) {
+				t.Errorf("problems = %q, want %q", inventory.Problems(), test.wantProblems)
+			}
+
+			netdev, ok := inventory.Get("v300")
+			if !ok {
+				t.Fatal("link v300 not found")
+			}
+			if netdev.Status != test.wantStatus {
+				t.Errorf("status = %s, want %s", netdev.Status, test.wantStatus)
+			}
+			if want := filepath.Join(root, test.wantUnit); netdev.Unit.Path != want {
+				t.Errorf("unit = %s, want %s", netdev.Unit.Path, want)
+			}
+
+			var shadowed []string
+			for _, path := range netdev.Shadowed {
+				shadowed = append(shadowed, strings.TrimPrefix(path, root+"/"))
+			}
+			if !reflect.DeepEqual(shadowed, test.wantShadowed) {
+				t.Errorf("shadowed = %q, want %q", shadowed, test.wantShadowed)
+			}
+		})
+	}
+}
+
+func TestInventoryFollowsChanges(t *testing.T) {
+	newTestRoot(t, map[string]string{
+		"etc/systemd/network/10-eth0.network":    "[Match]\nName=eth0\n",
+		"etc/linkctl/system/50-eth0.v300.netdev": vlanUnit("v300", "300"),
+	})
+
+	inventory := NewInventory()
+	netdev, ok := inventory.Get("v300")
+	if !ok {
+		t.Fatal("link v300 not found")
+	}
+
+	if err := Apply(func() error { return netdev.Rename("storage") }); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := inventory.Get("v300"); ok {
+		t.Error("link v300 still found after it was renamed")
+	}
+	if _, ok := inventory.Get("storage"); !ok {
+		t.Error("renamed link storage not found")
+	}
+
+	// Configure discards what was loaded from the previous root
+	newTestRoot(t, nil)
+	if links := inventory.List(true); len(links) != 0 {
+		t.Errorf("links = %v after changing the root, want none", links)
+	}
+}
@@ -0,0 +1,165 @@
+package networkd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// IFNAMSIZ less the terminating NUL
+const maxLinkNameLength = 15
+
+type kindInfo struct {
+	// Section is the unit section holding the kind-specific settings
+	Section string
+	// Attach is the [Network] key that attaches the link to the interface
+	// named in its unit. For stacked links, e.g. vlan, the interface is the
+	// parent; for bonds, bridges and VRFs it is a member. Kinds without an
+	// Attach key stand alone.
+	Attach string
+	// Sections are further sections links of the kind may use
+	Sections []string
+	// Master is set for kinds other interfaces are attached to as members
+	// with the Attach key
+	Master bool
+	// defaultName derives a link name when one is not provided
+	defaultName func(config *NetDevConfig) string
+	// validate checks the kind-specific settings, kinds without it cannot
+	// be created by linkctl
+	validate func(settings map[string]string) error
+}
+
+var tunnelKind = &kindInfo{Attach: "Tunnel", Section: "Tunnel"}
+
+var kinds = map[string]*kindInfo{
+	"vlan": {
+		Section: "VLAN",
+		Attach:  "VLAN",
+		defaultName: func(config *NetDevConfig) string {
+			return fmt.Sprintf("%s.%s", config.Parent, config.Settings["Id"])
+		},
+		validate: validateVLAN,
+	},
+	"macvlan": {Section: "MACVLAN", Attach: "MACVLAN"},
+	"macvtap": {Section: "MACVTAP", Attach: "MACVTAP"},
+	"ipvlan":  {Section: "IPVLAN", Attach: "IPVLAN"},
+	"ipvtap":  {Section: "IPVTAP", Attach: "IPVTAP"},
+	"vxlan":   {Section: "VXLAN", Attach: "VXLAN"},
+	"xfrm":    {Section: "Xfrm", Attach: "Xfrm"},
+	"macsec": {
+		Section:  "MACsec",
+		Attach:   "MACsec",
+		Sections: []string{"MACsecReceiveChannel", "MACsecTransmitAssociation", "MACsecReceiveAssociation"},
+	},
+	"ipoib":  {Section: "IPoIB", Attach: "IPoIB"},
+	"batadv": {Section: "BatmanAdvanced", Attach: "BatmanAdvanced"},
+
+	"gre":       tunnelKind,
+	"gretap":    tunnelKind,
+	"ip6gre":    tunnelKind,
+	"ip6gretap": tunnelKind,
+	"ipip":      tunnelKind,
+	"sit":       tunnelKind,
+	"vti":       tunnelKind,
+	"vti6":      tunnelKind,
+	"ip6tnl":    tunnelKind,
+	"erspan":    tunnelKind,
+
+	"bond":   {Section: "Bond", Attach: "Bond", Master: true},
+	"bridge": {Section: "Bridge", Attach: "Bridge", Master: true},
+	"vrf":    {Section: "VRF", Attach: "VRF", Master: true},
+
+	"dummy":     {},
+	"ifb":       {},
+	"nlmon":     {},
+	"vcan":      {},
+	"veth":      {Section: "Peer"},
+	"vxcan":     {Section: "VXCAN"},
+	"netdevsim": {},
+	"tun":       {Section: "Tun"},
+	"tap":       {Section: "Tap"},
+	"geneve":    {Section: "GENEVE"},
+	"bareudp":   {Section: "BareUDP"},
+	"fou":       {Section: "FooOverUDP"},
+	"l2tp":      {Section: "L2TP", Sections: []string{"L2TPSession"}},
+	"wlan":      {Section: "WLAN"},
+	"wireguard": {
+		Section:  "WireGuard",
+		Sections: []string{wireGuardPeerSection},
+		validate: validateWireGuard,
+	},
+}
+
+// Kinds returns the netdev kinds linkctl knows how to create
+func Kinds() []string {
+	var names []string
+	for name, kind := range kinds {
+		if kind.validate != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// attachKey returns the [Network] key used to attach a link of the given
+// kind to the interface named in its unit.
+func attachKey(kind string) (string, error) {
+	info, ok := kinds[kind]
+	if !ok {
+		return "", fmt.Errorf("Links of kind %s are not supported", kind)
+	}
+
+	if info.Attach == "" {
+		return "", fmt.Errorf("Links of kind %s are not attached to a parent interface", kind)
+	}
+
+	return info.Attach, nil
+}
+
+// isStandalone reports whether links of the kind have no parent interface
+func isStandalone(kind string) bool {
+	info, ok := kinds[kind]
+	return ok && info.Attach == ""
+}
+
+func validateVLAN(settings map[string]string) error {
+	value, ok := settings["Id"]
+	if !ok {
+		return fmt.Errorf("A VLAN Id is required")
+	}
+
+	id, err := strconv.Atoi(value)
+	if err != nil || id < 1 || id > 4094 {
+		return fmt.Errorf("The VLAN Id %s must be between 1 and 4094", value)
+	}
+
+	return nil
+}
+
+// ValidateLinkName checks a name against the kernel's rules for interface
+// names.
+func ValidateLinkName(name string) error {
+	if name == "" {
+		return fmt.Errorf("A link name is required")
+	}
+
+	if len(name) > maxLinkNameLength {
+		return fmt.Errorf("The link name %s is longer than %d characters",
+			name, maxLinkNameLength)
+	}
+
+	if name == "." || name == ".." {
+		return fmt.Errorf("The link name %s is not allowed", name)
+	}
+
+	if strings.IndexFunc(name, func(r rune) bool {
+		return r == '/' || r == ':' || unicode.IsSpace(r)
+	}) >= 0 {
+		return fmt.Errorf("The link name %s may not contain '/', ':' or whitespace", name)
+	}
+
+	return nil
+}
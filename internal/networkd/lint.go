@@ -0,0 +1,438 @@
+package networkd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type Severity string
+
+const (
+	// SeverityError findings keep systemd-networkd from creating a link as
+	// configured
+	SeverityError Severity = "error"
+	// SeverityWarning findings are likely mistakes, such as a key that
+	// systemd-networkd ignores
+	SeverityWarning Severity = "warning"
+)
+
+// Finding is an issue Lint found in a unit
+type Finding struct {
+	Severity Severity
+	// Path is the unit or drop-in responsible
+	Path    string
+	Link    string
+	Section string
+	Key     string
+	Message string
+}
+
+// netdevKeys are the keys systemd-networkd accepts in the sections of
+// netdev units. Sections without an entry are not checked for unknown keys.
+var netdevKeys = map[string][]string{
+	"Match": {"Host", "Virtualization", "KernelCommandLine", "KernelVersion",
+		"Credential", "Architecture", "Firmware"},
+	"NetDev": {"Description", "Name", "Kind", "MTUBytes", "MACAddress"},
+	"VLAN": {"Id", "Protocol", "GVRP", "MVRP", "LooseBinding", "ReorderHeader",
+		"EgressQOSMaps", "IngressQOSMaps"},
+	"MACVLAN": {"Mode", "SourceMACAddress", "BroadcastMulticastQueueLength"},
+	"MACVTAP": {"Mode", "SourceMACAddress", "BroadcastMulticastQueueLength"},
+	"IPVLAN":  {"Mode", "Flags"},
+	"IPVTAP":  {"Mode", "Flags"},
+	"VXLAN": {"VNI", "Id", "Remote", "Local", "Group", "TOS", "TTL",
+		"MacLearning", "FDBAgeingSec", "MaximumFDBEntries", "ReduceARPProxy",
+		"L2MissNotification", "L3MissNotification", "RouteShortCircuit",
+		"UDPChecksum", "UDP6ZeroChecksumTx", "UDP6ZeroChecksumRx",
+		"RemoteChecksumTx", "RemoteChecksumRx", "GroupPolicyExtension",
+		"GenericProtocolExtension", "DestinationPort", "PortRange",
+		"FlowLabel", "IPDoNotFragment", "Independent"},
+	"Tunnel": {"External", "Local", "Remote", "TOS", "TTL", "DiscoverPathMTU",
+		"IPv6FlowLabel", "CopyDSCP", "EncapsulationLimit", "Key", "InputKey",
+		"OutputKey", "Mode", "Independent", "AssignToLoopback",
+		"AllowLocalRemote", "FooOverUDP", "FOUDestinationPort", "FOUSourcePort",
+		"Encapsulation", "IPv6RapidDeploymentPrefix", "ISATAP",
+		"SerializeTunneledPackets", "ERSPANVersion", "ERSPANIndex",
+		"ERSPANDirection", "ERSPANHardwareId"},
+	"Peer": {"Name", "MACAddress"},
+	"VRF":  {"Table"},
+	"Tun":  {"MultiQueue", "PacketInfo", "VNetHeader", "User", "Group", "KeepCarrier"},
+	"Tap":  {"MultiQueue", "PacketInfo", "VNetHeader", "User", "Group", "KeepCarrier"},
+	"WireGuard": {"PrivateKey", "PrivateKeyFile", "ListenPort", "FirewallMark",
+		"RouteTable", "RouteMetric"},
+	"WireGuardPeer": {"PublicKey", "PresharedKey", "PresharedKeyFile",
+		"AllowedIPs", "Endpoint", "PersistentKeepalive", "RouteTable",
+		"RouteMetric"},
+}
+
+// maxVNI is the largest 24-bit VXLAN network identifier
+const maxVNI = 1<<24 - 1
+
+// linter collects the findings for a single unit
+type linter struct {
+	findings []Finding
+	link     string
+	path     string
+}
+
+func (self *linter) report(severity Severity, entry *EffectiveEntry, section string, format string, args ...interface{}) {
+	finding := Finding{
+		Severity: severity,
+		Path:     self.path,
+		Link:     self.link,
+		Section:  section,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if entry != nil {
+		finding.Path = entry.Source
+		finding.Key = entry.Key
+	}
+	self.findings = append(self.findings, finding)
+}
+
+// Lint checks the default inventory
+func Lint() ([]Finding, error) {
+	return defaultInventory.Lint()
+}
+
+// Lint checks every discovered netdev, and every network unit that
+// attaches a netdev to an interface, for settings systemd-networkd rejects
+// or ignores. Problems found while discovering links are reported as
+// errors. Masked links are not checked.
+func (self *Inventory) Lint() ([]Finding, error) {
+	if err := self.Load(); err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	for _, netdev := range self.List(true) {
+		if netdev.Status == LinkMasked {
+			continue
+		}
+
+		linkFindings, err := lintNetDev(netdev)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, linkFindings...)
+	}
+
+	networkFindings, err := self.lintNetworks()
+	if err != nil {
+		return nil, err
+	}
+	findings = append(findings, networkFindings...)
+
+	for _, problem := range self.Problems() {
+		findings = append(findings, lintProblem(problem))
+	}
+
+	return findings, nil
+}
+
+func lintNetDev(netdev *NetDev) ([]Finding, error) {
+	effective, err := netdev.Unit.Effective()
+	if err != nil {
+		return nil, err
+	}
+
+	lint := &linter{link: netdev.Name, path: netdev.Unit.Path}
+	lint.ignoredLines(append([]*Unit{netdev.Unit}, netdev.Dropins...))
+
+	name := effective.Get("NetDev", "Name")
+	if name == nil {
+		lint.report(SeverityError, nil, "NetDev", "The link has no Name")
+	} else if err := ValidateLinkName(name.Value); err != nil {
+		lint.report(SeverityError, name, "NetDev", "%v", err)
+	}
+
+	kindEntry := effective.Get("NetDev", "Kind")
+	if kindEntry == nil {
+		lint.report(SeverityError, nil, "NetDev", "The link %s has no Kind", netdev.Name)
+		return lint.findings, nil
+	}
+	kind, ok := kinds[kindEntry.Value]
+	if !ok {
+		lint.report(SeverityError, kindEntry, "NetDev", "Unknown link kind %s", kindEntry.Value)
+		return lint.findings, nil
+	}
+
+	sections := map[string]bool{"Match": true, "NetDev": true}
+	if kind.Section != "" {
+		sections[kind.Section] = true
+	}
+	for _, section := range kind.Sections {
+		sections[section] = true
+	}
+
+	for _, section := range effective.Sections {
+		if !sections[section.Name] {
+			var first *EffectiveEntry
+			if len(section.Entries) > 0 {
+				first = section.Entries[0]
+			}
+			lint.report(SeverityWarning, first, section.Name,
+				"The section [%s] is not used by %s links", section.Name, kindEntry.Value)
+			continue
+		}
+		lint.unknownKeys(section)
+	}
+
+	switch kindEntry.Value {
+	case "vlan":
+		lint.vlan(effective)
+	case "vxlan":
+		lint.vxlan(effective)
+	case "wireguard":
+		lint.wireGuard(effective)
+	}
+
+	return lint.findings, nil
+}
+
+// ignoredLines reports the lines of the units systemd-networkd ignores
+func (self *linter) ignoredLines(units []*Unit) {
+	for _, unit := range units {
+		for _, warning := range unit.File.Warnings() {
+			self.findings = append(self.findings, Finding{
+				Severity: SeverityWarning,
+				Path:     unit.Path,
+				Link:     self.link,
+				Message:  warning,
+			})
+		}
+	}
+}
+
+func (self *linter) unknownKeys(section *EffectiveSection) {
+	keys, ok := netdevKeys[section.Name]
+	if !ok {
+		return
+	}
+
+	known := make(map[string]bool)
+	for _, key := range keys {
+		known[key] = true
+	}
+
+	for _, entry := range section.Entries {
+		if !known[entry.Key] {
+			self.report(SeverityWarning, entry, section.Name,
+				"Unknown key %s in [%s]", entry.Key, section.Name)
+		}
+	}
+}
+
+func (self *linter) vlan(effective *EffectiveUnit) {
+	id := effective.Get("VLAN", "Id")
+	if id == nil {
+		self.report(SeverityError, nil, "VLAN", "A VLAN Id is required")
+		return
+	}
+
+	if err := validateVLAN(map[string]string{"Id": id.Value}); err != nil {
+		self.report(SeverityError, id, "VLAN", "%v", err)
+	}
+}
+
+func (self *linter) vxlan(effective *EffectiveUnit) {
+	// Id is the deprecated name of VNI
+	vni := effective.Get("VXLAN", "VNI")
+	if vni == nil {
+		vni = effective.Get("VXLAN", "Id")
+	}
+	if vni == nil {
+		// External VXLAN links get their VNI from the tunnel metadata
+		if entry := effective.Get("VXLAN", "External"); entry == nil || !parseBool(entry.Value) {
+			self.report(SeverityError, nil, "VXLAN", "A VXLAN VNI is required")
+		}
+		return
+	}
+
+	value, err := strconv.Atoi(vni.Value)
+	if err != nil || value < 0 || value > maxVNI {
+		self.report(SeverityError, vni, "VXLAN", "The VXLAN VNI %s must be between 0 and %d", vni.Value, maxVNI)
+	}
+}
+
+func (self *linter) wireGuard(effective *EffectiveUnit) {
+	settings := make(map[string]string)
+	if section := effective.Section("WireGuard"); section != nil {
+		for _, entry := range section.Entries {
+			settings[entry.Key] = entry.Value
+		}
+	}
+
+	if err := validateWireGuard(settings); err != nil {
+		var entry *EffectiveEntry
+		if settings["ListenPort"] != "" {
+			entry = effective.Get("WireGuard", "ListenPort")
+		}
+		self.report(SeverityError, entry, "WireGuard", "%v", err)
+	}
+
+	if entry := effective.Get("WireGuard", "PrivateKey"); entry != nil {
+		if err := validateKey(entry.Value); err != nil {
+			self.report(SeverityError, entry, "WireGuard", "The PrivateKey is not a valid WireGuard key")
+		}
+	} else if entry := effective.Get("WireGuard", "PrivateKeyFile"); entry != nil {
+		self.keyFile(entry, "WireGuard")
+	}
+
+	for _, section := range effective.Sections {
+		if section.Name == wireGuardPeerSection {
+			self.wireGuardPeer(section)
+		}
+	}
+}
+
+// keyFile checks that a file holds a WireGuard key. Files that cannot be
+// read for lack of permission are not checked.
+func (self *linter) keyFile(entry *EffectiveEntry, section string) {
+	data, err := readFile(options.Path(entry.Value))
+	switch {
+	case os.IsNotExist(err):
+		self.report(SeverityError, entry, section, "The key file %s does not exist", entry.Value)
+	case err != nil:
+	case validateKey(strings.TrimSpace(string(data))) != nil:
+		self.report(SeverityError, entry, section, "The key file %s does not hold a valid WireGuard key", entry.Value)
+	}
+}
+
+func (self *linter) wireGuardPeer(section *EffectiveSection) {
+	var publicKey *EffectiveEntry
+	for _, entry := range section.Entries {
+		switch entry.Key {
+		case "PublicKey":
+			publicKey = entry
+			if err := validateKey(entry.Value); err != nil {
+				self.report(SeverityError, entry, section.Name, "%v", err)
+			}
+		case "PresharedKey":
+			if err := validateKey(entry.Value); err != nil {
+				self.report(SeverityError, entry, section.Name, "The PresharedKey is not a valid WireGuard key")
+			}
+		case "PresharedKeyFile":
+			self.keyFile(entry, section.Name)
+		case "AllowedIPs":
+			for _, value := range strings.FieldsFunc(entry.Value, func(r rune) bool {
+				return r == ',' || r == ' '
+			}) {
+				if !isIPOrPrefix(value) {
+					self.report(SeverityError, entry, section.Name,
+						"The allowed IP %s is not an address or prefix", value)
+				}
+			}
+		}
+	}
+
+	if publicKey == nil {
+		var first *EffectiveEntry
+		if len(section.Entries) > 0 {
+			first = section.Entries[0]
+		}
+		self.report(SeverityError, first, section.Name, "A [WireGuardPeer] has no PublicKey")
+	}
+}
+
+func isIPOrPrefix(value string) bool {
+	if _, _, err := net.ParseCIDR(value); err == nil {
+		return true
+	}
+	return net.ParseIP(value) != nil
+}
+
+func parseBool(value string) bool {
+	switch strings.ToLower(value) {
+	case "1", "yes", "y", "true", "t", "on":
+		return true
+	}
+	return false
+}
+
+// lintNetworks checks that the links network units attach to interfaces
+// are defined and enabled
+func (self *Inventory) lintNetworks() ([]Finding, error) {
+	attachKeys := make(map[string]bool)
+	for _, kind := range kinds {
+		if kind.Attach != "" {
+			attachKeys[kind.Attach] = true
+		}
+	}
+
+	paths, err := networkUnits()
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	for _, path := range paths {
+		unit, err := NewUnit(path)
+		if err != nil {
+			findings = append(findings, lintProblem(newProblem(path, "", err)))
+			continue
+		}
+
+		dropins, err := unit.DropinUnits()
+		if err != nil {
+			findings = append(findings, lintProblem(newProblem(path, "", err)))
+			continue
+		}
+		units := append([]*Unit{unit}, dropins...)
+
+		lint := &linter{path: path}
+		lint.ignoredLines(units)
+		findings = append(findings, lint.findings...)
+		lint.findings = nil
+
+		section := newEffectiveUnit(units).Section("Network")
+		if section == nil {
+			continue
+		}
+
+		for _, entry := range section.Entries {
+			if !attachKeys[entry.Key] {
+				continue
+			}
+			for _, name := range splitWords(entry.Value) {
+				lint.link = name
+				self.lintAttached(lint, entry, name)
+			}
+		}
+		findings = append(findings, lint.findings...)
+	}
+
+	return findings, nil
+}
+
+func (self *Inventory) lintAttached(lint *linter, entry *EffectiveEntry, name string) {
+	netdev, ok := self.Get(name)
+	if !ok {
+		// Bridges, bonds and VRFs may be created by other tools
+		intf, err := NewInterface(name)
+		if err != nil {
+			lint.report(SeverityError, entry, "Network", "%v", err)
+		} else if intf.NetIf == nil {
+			lint.report(SeverityError, entry, "Network",
+				"%s=%s refers to a link that is not defined", entry.Key, name)
+		}
+		return
+	}
+
+	switch netdev.Status {
+	case LinkDisabled, LinkMasked:
+		lint.report(SeverityWarning, entry, "Network",
+			"%s=%s refers to a link that is %s", entry.Key, name, netdev.Status)
+	}
+}
+
+func lintProblem(problem Problem) Finding {
+	return Finding{
+		Severity: SeverityError,
+		Path:     problem.Path,
+		Link:     problem.Link,
+		Message:  problem.Message,
+	}
+}
@@ -0,0 +1,118 @@
+package networkd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	const (
+		network = "etc/systemd/network/10-eth0.network"
+		system  = "etc/linkctl/system/50-eth0.v300.netdev"
+		enabled = "etc/systemd/network/50-eth0.v300.netdev"
+		dropin  = "etc/systemd/network/10-eth0.network.d/50-eth0.v300.conf"
+	)
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		// want are the severity, relative path and key of every finding
+		want []string
+	}{
+		{
+			name: "clean",
+			files: map[string]string{
+				system:  vlanUnit("v300", "300"),
+				enabled: "-> /" + system,
+				dropin:  "[Network]\nVLAN=v300\n",
+			},
+		},
+		{
+			name: "masked links are not checked",
+			files: map[string]string{
+				system:  "[NetDev]\nName=v300\nKind=vlan\n",
+				enabled: "-> /dev/null",
+			},
+		},
+		{
+			name:  "missing VLAN Id",
+			files: map[string]string{system: "[NetDev]\nName=v300\nKind=vlan\n"},
+			want:  []string{"error " + system + " "},
+		},
+		{
+			name:  "VLAN Id out of range",
+			files: map[string]string{system: vlanUnit("v300", "4095")},
+			want:  []string{"error " + system + " Id"},
+		},
+		{
+			name:  "unknown kind",
+			files: map[string]string{system: "[NetDev]\nName=v300\nKind=nope\n"},
+			want:  []string{"error " + system + " Kind"},
+		},
+		{
+			name:  "unknown key",
+			files: map[string]string{system: vlanUnit("v300", "300") + "Color=blue\n"},
+			want:  []string{"warning " + system + " Color"},
+		},
+		{
+			name:  "unused section",
+			files: map[string]string{system: vlanUnit("v300", "300") + "\n[VXLAN]\nVNI=1\n"},
+			want:  []string{"warning " + system + " VNI"},
+		},
+		{
+			name:  "ignored line",
+			files: map[string]string{system: vlanUnit("v300", "300") + "Oops\n"},
+			want:  []string{"warning " + system + " "},
+		},
+		{
+			name: "drop-in overrides the unit",
+			files: map[string]string{
+				system: vlanUnit("v300", "300"),
+				"etc/systemd/network/50-eth0.v300.netdev.d/id.conf": "[VLAN]\nId=0\n",
+			},
+			want: []string{"error etc/systemd/network/50-eth0.v300.netdev.d/id.conf Id"},
+		},
+		{
+			name: "attached link is disabled",
+			files: map[string]string{
+				system: vlanUnit("v300", "300"),
+				dropin: "[Network]\nVLAN=v300\n",
+			},
+			want: []string{"warning " + dropin + " VLAN"},
+		},
+		{
+			name:  "attached link is not defined",
+			files: map[string]string{dropin: "[Network]\nVLAN=v300\n"},
+			want:  []string{"error " + dropin + " VLAN"},
+		},
+		{
+			name: "discovery problem",
+			files: map[string]string{
+				"etc/linkctl/system/v300.netdev": vlanUnit("v300", "300"),
+			},
+			want: []string{"error etc/linkctl/system/v300.netdev "},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.files[network] = "[Match]\nName=eth0\n"
+			root := newTestRoot(t, test.files)
+
+			findings, err := NewInventory().Lint()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, finding := range findings {
+				path := strings.TrimPrefix(finding.Path, root+"/")
+				got = append(got, string(finding.Severity)+" "+path+" "+finding.Key)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("findings = %q, want %q\n%v", got, test.want, findings)
+			}
+		})
+	}
+}
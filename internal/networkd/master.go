@@ -0,0 +1,246 @@
+package networkd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Member is an interface attached to a bridge, bond or VRF
+type Member struct {
+	Name string
+	// Configured is set when a network unit attaches the member
+	Configured bool
+	// Active is set when the kernel reports the member attached
+	Active bool
+}
+
+func masterKey(kind string) (string, error) {
+	if info, ok := kinds[kind]; !ok || !info.Master {
+		return "", fmt.Errorf("Links of kind %s do not have members", kind)
+	}
+	return attachKey(kind)
+}
+
+// memberDropin returns the drop-in linkctl uses to attach an interface to
+// a master of the given kind.
+func memberDropin(kind string, member string) (*Unit, error) {
+	network := NetworkFromIntf(member)
+	if network == nil || !network.Interface.present() {
+		return nil, fmt.Errorf("There is no interface \"%s\"", member)
+	}
+
+	if network.Unit == nil {
+		return nil, fmt.Errorf("Unable to determine network unit for interface %s",
+			member)
+	}
+
+	return network.Unit.NewDropin(kind)
+}
+
+// configuredMaster returns the master a network unit and its drop-ins
+// attach the interface to. Later drop-ins override earlier settings.
+func configuredMaster(unit *Unit, key string) (string, error) {
+	effective, err := unit.Effective()
+	if err != nil {
+		return "", err
+	}
+
+	if entry := effective.Get("Network", key); entry != nil {
+		return entry.Value, nil
+	}
+	return "", nil
+}
+
+func validateMaster(kind string, master string) error {
+	if netdev, ok := GetNetDev(master); ok {
+		if netdev.Kind != kind {
+			return fmt.Errorf("The link %s is a %s, not a %s", master, netdev.Kind, kind)
+		}
+		return nil
+	}
+
+	intf, err := NewInterface(master)
+	if err != nil {
+		return err
+	}
+	if intf.Link != nil && intf.Link.Kind == kind {
+		return nil
+	}
+
+	return fmt.Errorf("There is no %s named %s", kind, master)
+}
+
+// AddMember attaches an interface to a bridge, bond or VRF by writing a
+// drop-in for the network unit of the interface.
+func AddMember(kind string, master string, member string) error {
+	key, err := masterKey(kind)
+	if err != nil {
+		return err
+	}
+
+	if err := validateMaster(kind, master); err != nil {
+		return err
+	}
+
+	dropinUnit, err := memberDropin(kind, member)
+	if err != nil {
+		return err
+	}
+
+	network := NetworkFromIntf(member)
+	current, err := configuredMaster(network.Unit, key)
+	if err != nil {
+		return err
+	}
+	if current == master {
+		return fmt.Errorf("The interface %s is already a member of %s", member, master)
+	} else if current != "" && dropinUnit.Get("Network", key) != current {
+		return fmt.Errorf("The interface %s is a member of %s configured by %s",
+			member, current, SystemPath(network.Unit.Path))
+	}
+
+	touchLink(member)
+	touchLink(master)
+
+	if err := dropinUnit.Set("Network", key, master); err != nil {
+		return fmt.Errorf("Failed to update network unit %s: %w", dropinUnit.Path, err)
+	}
+
+	return nil
+}
+
+// RemoveMember detaches an interface previously attached with AddMember
+func RemoveMember(kind string, master string, member string) error {
+	key, err := masterKey(kind)
+	if err != nil {
+		return err
+	}
+
+	dropinUnit, err := memberDropin(kind, member)
+	if err != nil {
+		return err
+	}
+
+	if dropinUnit.Get("Network", key) != master {
+		return fmt.Errorf("The interface %s was not added to %s by linkctl", member, master)
+	}
+
+	touchLink(member)
+	touchLink(master)
+
+	if err := dropinUnit.Remove("Network", key); err != nil {
+		return fmt.Errorf("Failed to update network unit %s: %w", dropinUnit.Path, err)
+	}
+
+	return nil
+}
+
+// matchedNames returns the interfaces a Name= list refers to: the names it
+// lists literally, and the interfaces its patterns match
+func matchedNames(patterns []string, interfaces []*Interface) []string {
+	var names []string
+	if len(patterns) == 0 {
+		return names
+	}
+
+	// An inverted list matches every interface it does not name
+	matchLive := strings.HasPrefix(patterns[0], "!")
+	if !matchLive {
+		for _, pattern := range patterns {
+			if strings.ContainsAny(pattern, "*?[") {
+				matchLive = true
+			} else {
+				names = append(names, pattern)
+			}
+		}
+	}
+	if !matchLive {
+		return names
+	}
+
+	for _, intf := range interfaces {
+		if matchName(patterns, intf.Name) {
+			names = append(names, intf.Name)
+		}
+	}
+	return names
+}
+
+// ListMembers returns every master of the given kind with its members,
+// combining the configured network units with the kernel's view.
+func ListMembers(kind string) (map[string][]*Member, error) {
+	key, err := masterKey(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	masters := make(map[string]map[string]*Member)
+	member := func(master string, name string) *Member {
+		if masters[master] == nil {
+			masters[master] = make(map[string]*Member)
+		}
+		if masters[master][name] == nil {
+			masters[master][name] = &Member{Name: name}
+		}
+		return masters[master][name]
+	}
+
+	for _, netdev := range ListNetDev(true) {
+		if netdev.Kind == kind && masters[netdev.Name] == nil {
+			masters[netdev.Name] = make(map[string]*Member)
+		}
+	}
+
+	interfaces, err := ListInterfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := networkUnits()
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		unit, err := NewUnit(file)
+		if err != nil {
+			continue
+		}
+		master, err := configuredMaster(unit, key)
+		if err != nil || master == "" {
+			continue
+		}
+
+		effective, err := unit.Effective()
+		if err != nil {
+			continue
+		}
+		for _, name := range matchedNames(effective.Values("Match", "Name"), interfaces) {
+			member(master, name).Configured = true
+		}
+	}
+
+	for _, intf := range interfaces {
+		if intf.Link.Kind == kind && masters[intf.Name] == nil {
+			masters[intf.Name] = make(map[string]*Member)
+		}
+	}
+	for _, intf := range interfaces {
+		if _, ok := masters[intf.Link.Master]; ok {
+			member(intf.Link.Master, intf.Name).Active = true
+		}
+	}
+
+	result := make(map[string][]*Member)
+	for master, members := range masters {
+		result[master] = []*Member{}
+		for _, m := range members {
+			result[master] = append(result[master], m)
+		}
+		sort.Slice(result[master], func(i, j int) bool {
+			return result[master][i].Name < result[master][j].Name
+		})
+	}
+
+	return result, nil
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"sort"
 	"strings"
 )
 
@@ -13,48 +12,79 @@ type NetDev struct {
 	Kind              string
 	Description       string
 	Unit              *Unit
+	Dropins           []*Unit
 	Status            LinkStatus
 	RenameUnit        *Unit
 	RenameNetworkUnit *Unit
 	Interface         *Interface
 	Network           *Network
 	ParentNetwork     *Network
+	// Runtime is set for links enabled until the next reboot, whose symlink
+	// and parent drop-in live in RuntimeDir
+	Runtime bool
+	// Shadowed are the units that define a link with the same name but are
+	// ignored in favor of this one
+	Shadowed []string
+
+	inventory *Inventory
 }
 
 type LinkStatus string
 type LinkType string
 
 const (
-	LinkEnabled     = "enabled"
-	LinkDisabled    = "disabled"
-	LinkUserDefined = "user-defined"
+	LinkEnabled        = "enabled"
+	LinkEnabledRuntime = "enabled-runtime"
+	LinkDisabled       = "disabled"
+	LinkUserDefined    = "user-defined"
+	// LinkRuntime and LinkVendor links are defined in the runtime and vendor
+	// networkd directories, e.g. by a generator or a package
+	LinkRuntime = "runtime"
+	LinkVendor  = "vendor"
+	// LinkMasked links are shadowed by a symlink to /dev/null
+	LinkMasked = "masked"
 )
 
 const (
 	EnabledLink   LinkType = "enabled"
 	AvailableLink LinkType = "available"
+	RuntimeLink   LinkType = "runtime"
+	VendorLink    LinkType = "vendor"
+	MaskedLink    LinkType = "masked"
 )
 
-var netdevs map[string]*NetDev
-
 func (self *NetDev) Enable() error {
+	return self.enable(options.NetworkDir, LinkEnabled)
+}
+
+// EnableRuntime enables the link until the next reboot by linking its unit
+// into RuntimeDir.
+func (self *NetDev) EnableRuntime() error {
+	return self.enable(options.RuntimeDir, LinkEnabledRuntime)
+}
+
+func (self *NetDev) enable(dir string, status LinkStatus) error {
 	switch self.Status {
-	case LinkEnabled:
+	case LinkEnabled, LinkEnabledRuntime:
 		return fmt.Errorf("The link %s is already enabled", self.Name)
 	case LinkUserDefined:
 		return fmt.Errorf("The link %s is user-defined and cannot be enabled", self.Name)
+	case LinkRuntime, LinkVendor:
+		return fmt.Errorf("The link %s is defined in %s and cannot be enabled", self.Name, self.unitDir())
+	case LinkMasked:
+		return fmt.Errorf("The link %s is masked and cannot be enabled", self.Name)
 	}
 
-	self.Status = LinkEnabled
+	self.Status = status
+	self.Runtime = status == LinkEnabledRuntime
+	touchLink(self.Name)
 
-	if err := self.ParentNetwork.UpdateNetDev(self); err != nil {
+	if err := self.updateParent(); err != nil {
 		return err
 	}
 
-	linkedName := fmt.Sprintf(
-		"/etc/systemd/network/%s",
-		self.Unit.Name)
-	if err := os.Symlink(self.Unit.Path, linkedName); err != nil {
+	linkedName := filepath.Join(options.Path(dir), self.Unit.Name)
+	if err := symlink(options.SystemPath(self.Unit.Path), linkedName); err != nil {
 		return fmt.Errorf("Failed to create unit symlink %s", linkedName)
 	}
 
@@ -62,28 +92,158 @@ func (self *NetDev) Enable() error {
 }
 
 func (self *NetDev) Disable() error {
+	if self.Status == LinkEnabledRuntime {
+		return fmt.Errorf("The link %s is enabled until the next reboot, disable it with --runtime", self.Name)
+	}
+	return self.disable()
+}
+
+// DisableRuntime disables a link enabled until the next reboot
+func (self *NetDev) DisableRuntime() error {
+	if self.Status == LinkEnabled {
+		return fmt.Errorf("The link %s is enabled persistently, disable it without --runtime", self.Name)
+	}
+	return self.disable()
+}
+
+func (self *NetDev) disable() error {
 	switch self.Status {
 	case LinkDisabled:
 		return fmt.Errorf("The link %s is already disabled", self.Name)
 	case LinkUserDefined:
 		return fmt.Errorf("The link %s is user-defined and cannot be disabled", self.Name)
+	case LinkRuntime, LinkVendor:
+		return fmt.Errorf("The link %s is defined in %s and cannot be disabled", self.Name, self.unitDir())
+	case LinkMasked:
+		return fmt.Errorf("The link %s is masked and cannot be disabled", self.Name)
 	}
 
 	self.Status = LinkDisabled
 
-	if err := self.ParentNetwork.UpdateNetDev(self); err != nil {
+	if err := self.updateParent(); err != nil {
 		return err
 	}
 
-	err := os.Remove(self.Unit.Path)
+	err := removeFile(self.Unit.Path)
 	if err != nil {
 		return err
 	}
+	self.Runtime = false
 
 	return self.Interface.Delete()
 }
 
+// Delete removes the netdev definition along with every drop-in linkctl
+// created for it. User-defined netdevs are only deleted when forced.
+func (self *NetDev) Delete(force bool) error {
+	switch self.Status {
+	case LinkUserDefined:
+		if !force {
+			return fmt.Errorf("The link %s is user-defined and will only be deleted when forced", self.Name)
+		}
+	case LinkRuntime, LinkVendor, LinkMasked:
+		return fmt.Errorf("The link %s is defined in %s and cannot be deleted", self.Name, self.unitDir())
+	}
+
+	source := self.SourcePath()
+
+	parentDropins, err := self.parentDropins()
+	if err != nil {
+		return err
+	}
+	for _, dropin := range parentDropins {
+		if self.ParentNetwork != nil {
+			touchLink(self.ParentNetwork.Interface.Name)
+		}
+		if err := removeFile(dropin); err != nil {
+			return fmt.Errorf("Failed to remove parent unit %s: %w", dropin, err)
+		}
+		removeEmptyDir(filepath.Dir(dropin))
+	}
+
+	if self.RenameNetworkUnit != nil {
+		if err := self.RenameNetworkUnit.Delete(); err != nil {
+			return fmt.Errorf("Failed to remove dropin unit %s: %w", self.RenameNetworkUnit.Path, err)
+		}
+	}
+
+	// The drop-ins would otherwise apply to another definition with the
+	// same unit name
+	dropinDir := filepath.Join(options.Path(options.NetworkDir), self.Unit.Name+".d")
+	dropins, err := glob(filepath.Join(dropinDir, "*.conf"))
+	if err != nil {
+		return fmt.Errorf("Failed to list dropin units in %s: %w", dropinDir, err)
+	}
+	for _, dropin := range dropins {
+		if err := removeFile(dropin); err != nil {
+			return fmt.Errorf("Failed to remove dropin unit %s: %w", dropin, err)
+		}
+	}
+	removeEmptyDir(dropinDir)
+
+	if source != self.Unit.Path {
+		if err := removeFile(self.Unit.Path); err != nil {
+			return fmt.Errorf("Failed to remove unit symlink %s: %w", self.Unit.Path, err)
+		}
+	}
+
+	if err := removeFile(source); err != nil {
+		return fmt.Errorf("Failed to remove unit %s: %w", source, err)
+	}
+
+	if self.Status != LinkDisabled {
+		if err := self.Interface.Delete(); err != nil {
+			return err
+		}
+	}
+
+	self.inventory.remove(self)
+	return nil
+}
+
+// parentDropins returns the drop-ins attaching the netdev to its parent
+// network. They are found by name rather than through the parent network so
+// they can be removed when the parent interface no longer exists.
+func (self *NetDev) parentDropins() ([]string, error) {
+	dropinName := strings.TrimSuffix(self.Unit.Name, ".netdev") + ".conf"
+
+	var dropins []string
+	for _, dir := range []string{options.NetworkDir, options.RuntimeDir} {
+		pattern := filepath.Join(options.Path(dir), "*.network.d", dropinName)
+		matches, err := glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to list parent units at %s: %w", pattern, err)
+		}
+		dropins = append(dropins, matches...)
+	}
+	return dropins, nil
+}
+
+// unitDir returns the directory defining the netdev as the running system
+// sees it
+func (self *NetDev) unitDir() string {
+	return SystemPath(filepath.Dir(self.Unit.Path))
+}
+
+// SourcePath returns the file defining the netdev, following the symlink
+// of an enabled netdev back to its available definition.
+func (self *NetDev) SourcePath() string {
+	if self.Status != LinkEnabled && self.Status != LinkEnabledRuntime {
+		return self.Unit.Path
+	}
+
+	target, err := os.Readlink(self.Unit.Path)
+	if err != nil {
+		return self.Unit.Path
+	}
+	if !filepath.IsAbs(target) {
+		return filepath.Join(filepath.Dir(self.Unit.Path), target)
+	}
+	return options.Path(target)
+}
+
 func (self *NetDev) Rename(newName string) error {
+	oldName := self.Name
 	if self.RenameUnit == nil {
 		unit, err := self.Unit.NewDropin("name")
 		if err != nil {
@@ -120,8 +280,9 @@ func (self *NetDev) Rename(newName string) error {
 	if err := self.Reload(); err != nil {
 		return err
 	}
+	self.inventory.rename(self, oldName)
 
-	if err := self.ParentNetwork.UpdateNetDev(self); err != nil {
+	if err := self.updateParent(); err != nil {
 		return err
 	}
 
@@ -129,6 +290,7 @@ func (self *NetDev) Rename(newName string) error {
 }
 
 func (self *NetDev) ResetName() error {
+	oldName := self.Name
 	if self.RenameUnit != nil {
 		if err := self.RenameUnit.Remove("NetDev", "Name"); err != nil {
 			return err
@@ -146,14 +308,65 @@ func (self *NetDev) ResetName() error {
 	if err := self.Reload(); err != nil {
 		return err
 	}
+	self.inventory.rename(self, oldName)
 
-	if err := self.ParentNetwork.UpdateNetDev(self); err != nil {
+	if err := self.updateParent(); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// SetDescription overrides the description of the link with a drop-in. An
+// empty description clears it. Setting the description the unit already
+// has removes the override.
+func (self *NetDev) SetDescription(description string) error {
+	unit, err := self.Unit.NewDropin("description")
+	if err != nil {
+		return fmt.Errorf("Failed to create dropin for unit %s: %w", self.Unit.Path, err)
+	}
+
+	if description == self.Unit.Get("NetDev", "Description") {
+		err = unit.Remove("NetDev", "Description")
+	} else {
+		// An empty assignment is kept so it resets the unit's description
+		unit.File.Section("NetDev").Set("Description", description)
+		err = unit.Save()
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to save dropin unit %s: %w", unit.Path, err)
+	}
+
+	touchLink(self.Name)
+
+	return self.Reload()
+}
+
+// updateParent attaches or detaches the link in the network of the
+// interface named in its unit, standalone kinds have nothing to update.
+func (self *NetDev) updateParent() error {
+	if isStandalone(self.Kind) {
+		return nil
+	}
+
+	if _, known := kinds[self.Kind]; !known && self.Status == LinkDisabled {
+		// A link of an unknown kind may have no parent, only detach it from
+		// a parent that can be found
+		parent := self.ParentNetwork
+		if parent == nil || !parent.Interface.present() || parent.Unit == nil {
+			return nil
+		}
+	}
+
+	if self.ParentNetwork == nil {
+		return fmt.Errorf(
+			"Unable to determine the parent interface for link %s (%s)",
+			self.Name, self.Unit.Name)
+	}
+
+	return self.ParentNetwork.UpdateNetDev(self)
+}
+
 func (self *NetDev) Reload() error {
 	if err := self.loadUnit(); err != nil {
 		return err
@@ -175,7 +388,7 @@ func (self *NetDev) parseUnitName() (string, error) {
 	}
 
 	networkParts := strings.SplitN(nameParts[1], ".", 2)
-	if len(networkParts) != 2 {
+	if len(networkParts) != 2 || networkParts[0] == "" {
 		return "", fmt.Errorf(
 			"link %s does not have a conforming unit name (%s)",
 			self.Name, self.Unit.Name)
@@ -185,51 +398,46 @@ func (self *NetDev) parseUnitName() (string, error) {
 	return intfName, nil
 }
 
+// loadUnit applies the effective configuration of the unit and its
+// drop-ins.
 func (self *NetDev) loadUnit() error {
-	self.applyConfig(self.Unit)
-
-	if err := self.applyDropinConfigs(); err != nil {
+	dropins, err := self.Unit.DropinUnits()
+	if err != nil {
 		return err
 	}
+	self.Dropins = dropins
+	effective := newEffectiveUnit(append([]*Unit{self.Unit}, self.Dropins...))
 
-	return nil
-}
+	if entry := effective.Get("NetDev", "Name"); entry != nil {
+		self.Name = entry.Value
+		// A failed lookup is reported by the inventory, which looks up the
+		// same interface for the netdev's network
+		self.Interface, _ = NewInterface(self.Name)
+		self.RenameUnit = self.renameDropin(entry.Source)
+	}
 
-func (self *NetDev) applyConfig(unit *Unit) {
-	section := unit.File.Section("NetDev")
-
-	var keys = []string{"Name", "Kind", "Description"}
-
-	for _, key := range keys {
-		if section.HasKey(key) {
-			value := section.Key(key).String()
-			switch key {
-			case "Name":
-				self.Name = value
-				self.Interface = NewInterface(self.Name)
-			case "Kind":
-				self.Kind = value
-			case "Description":
-				self.Description = value
-			}
-		}
+	if entry := effective.Get("NetDev", "Kind"); entry != nil {
+		self.Kind = entry.Value
 	}
-}
 
-func (self *NetDev) applyDropinConfigs() error {
-	if self.Unit == nil {
-		return nil
+	self.Description = ""
+	if entry := effective.Get("NetDev", "Description"); entry != nil {
+		self.Description = entry.Value
 	}
 
-	for unit := range self.Unit.DropinUnits() {
-		origName := self.Name
-		self.applyConfig(unit)
+	return nil
+}
+
+// renameDropin returns the drop-in linkctl may edit to rename the link if it
+// is the source of the link's name.
+func (self *NetDev) renameDropin(source string) *Unit {
+	dropinDir := filepath.Join(options.Path(options.NetworkDir), self.Unit.Name+".d")
 
-		if self.Name != origName {
-			self.RenameUnit = unit
+	for _, unit := range self.Dropins {
+		if unit.Path == source && filepath.Dir(unit.Path) == dropinDir {
+			return unit
 		}
 	}
-
 	return nil
 }
 
@@ -238,7 +446,20 @@ func (self *NetDev) findNetworkDropin() error {
 		return nil
 	}
 
-	for unit := range self.Network.Unit.DropinUnits() {
+	dropins, err := self.Network.Unit.DropinUnits()
+	if err != nil {
+		return err
+	}
+
+	// Only drop-ins in NetworkDir are linkctl's to edit, those in RuntimeDir
+	// and VendorDir belong to others
+	dropinDir := filepath.Join(options.Path(options.NetworkDir), self.Network.Unit.Name+".d")
+
+	for _, unit := range dropins {
+		if filepath.Dir(unit.Path) != dropinDir {
+			continue
+		}
+
 		match := unit.Get("Match", "Name")
 		for _, name := range strings.Split(match, " ") {
 			if name == self.Name {
@@ -251,12 +472,18 @@ func (self *NetDev) findNetworkDropin() error {
 	return nil
 }
 
+// NewNetDev loads the netdev defined by the unit at path. The netdev is not
+// added to any inventory.
 func NewNetDev(path string, linkType LinkType) (*NetDev, error) {
-	var netdev NetDev
+	return defaultInventory.newNetDev(path, linkType)
+}
+
+func (self *Inventory) newNetDev(path string, linkType LinkType) (*NetDev, error) {
+	netdev := NetDev{inventory: self}
 
 	unit, err := NewUnit(path)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to load unit %s: %w", path, err)
+		return nil, err
 	}
 	netdev.Unit = unit
 
@@ -264,16 +491,18 @@ func NewNetDev(path string, linkType LinkType) (*NetDev, error) {
 		return nil, err
 	}
 
-	intfName, _ := netdev.parseUnitName()
-	netdev.Network = NetworkFromIntf(netdev.Name)
-	netdev.ParentNetwork = NetworkFromIntf(intfName)
+	netdev.Network = self.Network(netdev.Name)
+	if !isStandalone(netdev.Kind) {
+		intfName, _ := netdev.parseUnitName()
+		netdev.ParentNetwork = self.Network(intfName)
+	}
 
 	if err := netdev.findNetworkDropin(); err != nil {
 		return nil, err
 	}
 
-	netdev.Status = LinkDisabled
-	if linkType == EnabledLink {
+	switch linkType {
+	case EnabledLink:
 		fileInfo, err := os.Lstat(path)
 		if err != nil {
 			return nil, err
@@ -284,65 +513,25 @@ func NewNetDev(path string, linkType LinkType) (*NetDev, error) {
 		} else {
 			netdev.Status = LinkUserDefined
 		}
-	}
-
-	return &netdev, nil
-}
-
-func loadNetDevs() {
-	linkTypes := [2]LinkType{EnabledLink, AvailableLink}
-	configPaths := map[LinkType][]string{
-		EnabledLink:   []string{"/etc/systemd/network/*.netdev"},
-		AvailableLink: []string{"/etc/linkctl/user/*.netdev", "/etc/linkctl/system/*.netdev", "/etc/systemd/network/netdev.available/*.netdev"},
-	}
-
-	if netdevs != nil {
-		return
-	}
-
-	netdevs = make(map[string]*NetDev)
-	for _, linkType := range linkTypes {
-		for _, path := range configPaths[linkType] {
-			files, err := filepath.Glob(path)
-			if err != nil {
-				panic(err)
-			}
-
-			for _, file := range files {
-				netdev, err := NewNetDev(file, linkType)
-				if err != nil {
-					continue
-				}
-				_, exist := netdevs[netdev.Name]
-				if !exist {
-					netdevs[netdev.Name] = netdev
-				}
-			}
+	case RuntimeLink:
+		fileInfo, err := os.Lstat(path)
+		if err != nil {
+			return nil, err
 		}
-	}
-}
 
-func ListNetDev(listAll bool) []*NetDev {
-	loadNetDevs()
-
-	var keys []string
-	var values []*NetDev
-	for k, v := range netdevs {
-		if listAll || v.Status != LinkDisabled {
-			keys = append(keys, k)
+		if fileInfo.Mode()&os.ModeSymlink == os.ModeSymlink {
+			netdev.Status = LinkEnabledRuntime
+			netdev.Runtime = true
+		} else {
+			netdev.Status = LinkRuntime
 		}
+	case VendorLink:
+		netdev.Status = LinkVendor
+	case MaskedLink:
+		netdev.Status = LinkMasked
+	default:
+		netdev.Status = LinkDisabled
 	}
-	sort.Strings(keys)
-
-	for _, k := range keys {
-		values = append(values, netdevs[k])
-	}
-	return values
-}
 
-func GetNetDev(linkName string) (*NetDev, bool) {
-	loadNetDevs()
-
-	link, ok := netdevs[linkName]
-	return link, ok
+	return &netdev, nil
 }
@@ -0,0 +1,208 @@
+package networkd
+
+import (
+	"testing"
+)
+
+func TestDelete(t *testing.T) {
+	const (
+		network    = "etc/systemd/network/10-eth0.network"
+		available  = "etc/linkctl/system/50-eth0.v300.netdev"
+		enabled    = "etc/systemd/network/50-eth0.v300.netdev"
+		dropinDir  = "etc/systemd/network/50-eth0.v300.netdev.d"
+		parentConf = "etc/systemd/network/10-eth0.network.d/50-eth0.v300.conf"
+	)
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		force   bool
+		wantErr bool
+		want    map[string]bool
+	}{
+		{
+			name: "disabled",
+			files: map[string]string{
+				available: vlanUnit("v300", "300"),
+			},
+			want: map[string]bool{available: false},
+		},
+		{
+			name: "drop-ins",
+			files: map[string]string{
+				available:                       vlanUnit("v300", "300"),
+				dropinDir + "/description.conf": "[NetDev]\nDescription=Storage\n",
+				dropinDir + "/mtu.conf":         "[NetDev]\nMTUBytes=9000\n",
+			},
+			want: map[string]bool{
+				available:                       false,
+				dropinDir + "/description.conf": false,
+				dropinDir + "/mtu.conf":         false,
+				dropinDir:                       false,
+			},
+		},
+		{
+			name: "enabled",
+			files: map[string]string{
+				network:    "[Match]\nName=eth0\n",
+				available:  vlanUnit("v300", "300"),
+				enabled:    "-> /" + available,
+				parentConf: "[Network]\nVLAN=v300\n",
+			},
+			want: map[string]bool{
+				available:  false,
+				enabled:    false,
+				parentConf: false,
+				network:    true,
+			},
+		},
+		{
+			name: "enabled without a parent network",
+			files: map[string]string{
+				available:  vlanUnit("v300", "300"),
+				enabled:    "-> /" + available,
+				parentConf: "[Network]\nVLAN=v300\n",
+			},
+			want: map[string]bool{
+				available:  false,
+				enabled:    false,
+				parentConf: false,
+			},
+		},
+		{
+			name: "user-defined",
+			files: map[string]string{
+				enabled: vlanUnit("v300", "300"),
+			},
+			wantErr: true,
+			want:    map[string]bool{enabled: true},
+		},
+		{
+			name: "user-defined forced",
+			files: map[string]string{
+				enabled: vlanUnit("v300", "300"),
+			},
+			force: true,
+			want:  map[string]bool{enabled: false},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := newTestRoot(t, test.files)
+
+			netdev, ok := GetNetDev("v300")
+			if !ok {
+				t.Fatalf("link v300 not found, problems: %v", Problems())
+			}
+
+			err := Apply(func() error {
+				return netdev.Delete(test.force)
+			})
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Delete error = %v, want error %v", err, test.wantErr)
+			}
+
+			checkFiles(t, root, test.want)
+			if _, ok := GetNetDev("v300"); ok != test.wantErr {
+				t.Errorf("link v300 found = %v after Delete", ok)
+			}
+		})
+	}
+}
+
+func TestEnableDisable(t *testing.T) {
+	const (
+		available   = "etc/linkctl/system/50-eth0.v300.netdev"
+		enabled     = "etc/systemd/network/50-eth0.v300.netdev"
+		runtime     = "run/systemd/network/50-eth0.v300.netdev"
+		parentConf  = "etc/systemd/network/10-eth0.network.d/50-eth0.v300.conf"
+		runtimeConf = "run/systemd/network/10-eth0.network.d/50-eth0.v300.conf"
+	)
+
+	tests := []struct {
+		name       string
+		change     func(netdev *NetDev) error
+		wantStatus LinkStatus
+		want       map[string]bool
+	}{
+		{
+			name:       "enable",
+			change:     (*NetDev).Enable,
+			wantStatus: LinkEnabled,
+			want:       map[string]bool{enabled: true, parentConf: true, runtime: false},
+		},
+		{
+			name:       "enable runtime",
+			change:     (*NetDev).EnableRuntime,
+			wantStatus: LinkEnabledRuntime,
+			want:       map[string]bool{runtime: true, runtimeConf: true, enabled: false},
+		},
+		{
+			name: "enable and disable",
+			change: func(netdev *NetDev) error {
+				if err := netdev.Enable(); err != nil {
+					return err
+				}
+				if err := LoadNetDevs(); err != nil {
+					return err
+				}
+				netdev, _ = GetNetDev("v300")
+				return netdev.Disable()
+			},
+			wantStatus: LinkDisabled,
+			want:       map[string]bool{enabled: false, parentConf: false, available: true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := newTestRoot(t, map[string]string{
+				"etc/systemd/network/10-eth0.network": "[Match]\nName=eth0\n",
+				available:                             vlanUnit("v300", "300"),
+			})
+
+			netdev, ok := GetNetDev("v300")
+			if !ok {
+				t.Fatalf("link v300 not found, problems: %v", Problems())
+			}
+
+			if err := Apply(func() error { return test.change(netdev) }); err != nil {
+				t.Fatal(err)
+			}
+
+			checkFiles(t, root, test.want)
+			if err := LoadNetDevs(); err != nil {
+				t.Fatal(err)
+			}
+			if netdev, ok := GetNetDev("v300"); !ok {
+				t.Errorf("link v300 not found after the change")
+			} else if netdev.Status != test.wantStatus {
+				t.Errorf("status = %s, want %s", netdev.Status, test.wantStatus)
+			}
+		})
+	}
+}
+
+func TestEnableAttachKey(t *testing.T) {
+	const parentConf = "etc/systemd/network/10-eth0.network.d/50-eth0.mv0.conf"
+
+	root := newTestRoot(t, map[string]string{
+		"etc/systemd/network/10-eth0.network": "[Match]\nName=eth0\n",
+		"etc/linkctl/system/50-eth0.mv0.netdev": "[NetDev]\nName=mv0\nKind=macvlan\n\n" +
+			"[MACVLAN]\nMode=bridge\n",
+	})
+
+	netdev, ok := GetNetDev("mv0")
+	if !ok {
+		t.Fatalf("link mv0 not found, problems: %v", Problems())
+	}
+	if err := Apply(netdev.Enable); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "[Network]\nMACVLAN=mv0\n"
+	if got := readTestFile(t, root, parentConf); got != want {
+		t.Errorf("%s = %q, want %q", parentConf, got, want)
+	}
+}
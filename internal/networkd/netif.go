@@ -1,38 +1,273 @@
 package networkd
 
 import (
+	"errors"
 	"fmt"
 	"net"
-	"os/exec"
+
+	"golang.org/x/sys/unix"
 )
 
+// IFLA_VLAN_ID within IFLA_INFO_DATA of a vlan link
+const iflaVlanId = 1
+
+var operStates = []string{
+	"unknown", "notpresent", "down", "lowerlayerdown", "testing", "dormant", "up",
+}
+
 type Interface struct {
 	Name  string
 	NetIf *net.Interface
+	// Link holds the kernel's view of the interface, nil if it does not exist
+	Link *LinkAttrs
+}
+
+// LinkAttrs are the attributes of a kernel link reported by rtnetlink
+type LinkAttrs struct {
+	Index     int
+	Kind      string
+	OperState string
+	// Master is the bridge or bond the link belongs to
+	Master string
+	// Parent is the link a vlan, macvlan or similar is stacked on
+	Parent string
+	VlanId int
+}
+
+// present reports whether the interface can be configured. Under another
+// root the running system's links are unknown, so every interface is
+// assumed to be present.
+func (self *Interface) present() bool {
+	return self.NetIf != nil || !options.IsLive()
 }
 
 func (self *Interface) Delete() error {
-	cmd := exec.Command("ip", "link", "show", self.Name)
-	if err := cmd.Run(); err != nil {
+	// Never touch the running system's links when operating on another root
+	if !options.IsLive() {
+		return nil
+	}
+
+	conn, err := dialNetlink(unix.NETLINK_ROUTE)
+	if err != nil {
+		return fmt.Errorf("Unable to delete link %s: %w", self.Name, err)
+	}
+	defer conn.Close()
+
+	link, _, err := getLink(conn, self.Name, 0)
+	if errors.Is(err, unix.ENODEV) {
 		return nil
+	} else if err != nil {
+		return fmt.Errorf("Unable to delete link %s: %w", self.Name, err)
 	}
 
-	cmd = exec.Command("ip", "link", "del", self.Name)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("unable to delete link %s", self.Name)
+	if plan != nil {
+		plan.record(&Change{Type: ChangeDeleteLink, Path: self.Name})
+		return nil
 	}
 
+	request := make([]byte, unix.SizeofIfInfomsg)
+	nativeEndian.PutUint32(request[4:8], uint32(link.Index))
+	if _, err := conn.Execute(unix.RTM_DELLINK, 0, request); err != nil && !errors.Is(err, unix.ENODEV) {
+		return fmt.Errorf("Unable to delete link %s: %w", self.Name, err)
+	}
+
+	self.Link = nil
+	self.NetIf = nil
 	return nil
 }
 
-func NewInterface(name string) *Interface {
+// OperState returns the operational state systemd-networkd reports for
+// the interface, falling back to the kernel's state for links networkd
+// does not manage, or "absent" if the interface does not exist.
+func (self *Interface) OperState() string {
+	if self.NetIf == nil {
+		return "absent"
+	}
+
+	state, err := readLinkState(self.NetIf.Index)
+	if err == nil && state["OPER_STATE"] != "" {
+		return state["OPER_STATE"]
+	}
+
+	if self.Link != nil {
+		return self.Link.OperState
+	}
+	return "unknown"
+}
+
+// getLink looks up a link by name, or by index if name is empty
+func getLink(conn *netlinkConn, name string, index int) (*LinkAttrs, *net.Interface, error) {
+	request := make([]byte, unix.SizeofIfInfomsg)
+	nativeEndian.PutUint32(request[4:8], uint32(index))
+	if name != "" {
+		request = append(request, encodeStringAttr(unix.IFLA_IFNAME, name)...)
+	}
+
+	replies, err := conn.Execute(unix.RTM_GETLINK, 0, request)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, reply := range replies {
+		if reply.Type == unix.RTM_NEWLINK && len(reply.Data) >= unix.SizeofIfInfomsg {
+			link, netif := parseLink(reply.Data)
+			return link, netif, nil
+		}
+	}
+
+	return nil, nil, unix.ENODEV
+}
+
+func parseLink(data []byte) (*LinkAttrs, *net.Interface) {
+	var link LinkAttrs
+	var netif net.Interface
+	var masterIndex, parentIndex uint32
+
+	link.Index = int(int32(nativeEndian.Uint32(data[4:8])))
+	netif.Index = link.Index
+	netif.Flags = linkFlags(nativeEndian.Uint32(data[8:12]))
+
+	for _, attr := range parseAttrs(data[unix.SizeofIfInfomsg:]) {
+		switch attr.Type {
+		case unix.IFLA_IFNAME:
+			netif.Name = attrString(attr.Data)
+		case unix.IFLA_MTU:
+			netif.MTU = int(attrUint32(attr.Data))
+		case unix.IFLA_ADDRESS:
+			netif.HardwareAddr = net.HardwareAddr(append([]byte(nil), attr.Data...))
+		case unix.IFLA_OPERSTATE:
+			if len(attr.Data) > 0 && int(attr.Data[0]) < len(operStates) {
+				link.OperState = operStates[attr.Data[0]]
+			}
+		case unix.IFLA_MASTER:
+			masterIndex = attrUint32(attr.Data)
+		case unix.IFLA_LINK:
+			parentIndex = attrUint32(attr.Data)
+		case unix.IFLA_LINKINFO:
+			parseLinkInfo(&link, attr.Data)
+		}
+	}
+
+	link.Master = linkName(masterIndex)
+	if parentIndex != uint32(link.Index) {
+		link.Parent = linkName(parentIndex)
+	}
+
+	return &link, &netif
+}
+
+func parseLinkInfo(link *LinkAttrs, data []byte) {
+	for _, attr := range parseAttrs(data) {
+		switch attr.Type {
+		case unix.IFLA_INFO_KIND:
+			link.Kind = attrString(attr.Data)
+		case unix.IFLA_INFO_DATA:
+			if link.Kind != "vlan" {
+				continue
+			}
+			for _, info := range parseAttrs(attr.Data) {
+				if info.Type == iflaVlanId && len(info.Data) >= 2 {
+					link.VlanId = int(nativeEndian.Uint16(info.Data))
+				}
+			}
+		}
+	}
+}
+
+func linkFlags(flags uint32) net.Flags {
+	var netFlags net.Flags
+
+	if flags&unix.IFF_UP != 0 {
+		netFlags |= net.FlagUp
+	}
+	if flags&unix.IFF_BROADCAST != 0 {
+		netFlags |= net.FlagBroadcast
+	}
+	if flags&unix.IFF_LOOPBACK != 0 {
+		netFlags |= net.FlagLoopback
+	}
+	if flags&unix.IFF_POINTOPOINT != 0 {
+		netFlags |= net.FlagPointToPoint
+	}
+	if flags&unix.IFF_MULTICAST != 0 {
+		netFlags |= net.FlagMulticast
+	}
+
+	return netFlags
+}
+
+// linkName resolves a link index to its name, links in other namespaces
+// and unknown indexes have no name.
+func linkName(index uint32) string {
+	if index == 0 {
+		return ""
+	}
+
+	netif, err := net.InterfaceByIndex(int(index))
+	if err != nil {
+		return ""
+	}
+	return netif.Name
+}
+
+// ListInterfaces returns every link in the kernel, or none when operating
+// on another root
+func ListInterfaces() ([]*Interface, error) {
+	if !options.IsLive() {
+		return nil, nil
+	}
+
+	conn, err := dialNetlink(unix.NETLINK_ROUTE)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	replies, err := conn.Execute(unix.RTM_GETLINK, unix.NLM_F_DUMP, make([]byte, unix.SizeofIfInfomsg))
+	if err != nil {
+		return nil, err
+	}
+
+	var interfaces []*Interface
+	for _, reply := range replies {
+		if reply.Type != unix.RTM_NEWLINK || len(reply.Data) < unix.SizeofIfInfomsg {
+			continue
+		}
+		link, netif := parseLink(reply.Data)
+		interfaces = append(interfaces, &Interface{
+			Name:  netif.Name,
+			NetIf: netif,
+			Link:  link,
+		})
+	}
+
+	return interfaces, nil
+}
+
+// NewInterface looks up the kernel link with the given name. An interface
+// that does not exist has a nil NetIf and Link, an error is returned only
+// when the kernel cannot be asked. The running system's links are not
+// looked up when operating on another root.
+func NewInterface(name string) (*Interface, error) {
 	intf := Interface{Name: name}
+	if !options.IsLive() {
+		return &intf, nil
+	}
+
+	conn, err := dialNetlink(unix.NETLINK_ROUTE)
+	if err != nil {
+		return &intf, fmt.Errorf("Unable to look up link %s: %w", name, err)
+	}
+	defer conn.Close()
 
-	netif, err := net.InterfaceByName(name)
-	if err == nil {
-		// TODO log
-		intf.NetIf = netif
+	link, netif, err := getLink(conn, name, 0)
+	if errors.Is(err, unix.ENODEV) {
+		return &intf, nil
+	} else if err != nil {
+		return &intf, fmt.Errorf("Unable to look up link %s: %w", name, err)
 	}
 
-	return &intf
+	intf.Link = link
+	intf.NetIf = netif
+	return &intf, nil
 }
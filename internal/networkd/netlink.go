@@ -0,0 +1,173 @@
+package networkd
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Netlink messages use the host byte order
+var nativeEndian binary.ByteOrder
+
+func init() {
+	one := uint16(1)
+	if *(*byte)(unsafe.Pointer(&one)) == 1 {
+		nativeEndian = binary.LittleEndian
+	} else {
+		nativeEndian = binary.BigEndian
+	}
+}
+
+type netlinkConn struct {
+	fd  int
+	seq uint32
+}
+
+type netlinkMessage struct {
+	Type uint16
+	Data []byte
+}
+
+type netlinkAttr struct {
+	Type uint16
+	Data []byte
+}
+
+func dialNetlink(protocol int) (*netlinkConn, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, protocol)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		unix.Close(fd)
+		return nil, os.NewSyscallError("bind", err)
+	}
+
+	return &netlinkConn{fd: fd}, nil
+}
+
+func (self *netlinkConn) Close() error {
+	return unix.Close(self.fd)
+}
+
+// Execute sends a request and collects the replies. Requests are
+// acknowledged so that failures are always reported as an error.
+func (self *netlinkConn) Execute(msgType uint16, flags uint16, payload []byte) ([]netlinkMessage, error) {
+	self.seq++
+	seq := self.seq
+
+	request := make([]byte, unix.SizeofNlMsghdr, unix.SizeofNlMsghdr+len(payload))
+	request = append(request, payload...)
+	nativeEndian.PutUint32(request[0:4], uint32(len(request)))
+	nativeEndian.PutUint16(request[4:6], msgType)
+	nativeEndian.PutUint16(request[6:8], flags|unix.NLM_F_REQUEST|unix.NLM_F_ACK)
+	nativeEndian.PutUint32(request[8:12], seq)
+
+	if err := unix.Sendto(self.fd, request, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return nil, os.NewSyscallError("sendto", err)
+	}
+
+	var replies []netlinkMessage
+	buf := make([]byte, os.Getpagesize()*8)
+	for {
+		n, _, err := unix.Recvfrom(self.fd, buf, 0)
+		if err != nil {
+			return nil, os.NewSyscallError("recvfrom", err)
+		}
+
+		data := buf[:n]
+		for len(data) >= unix.SizeofNlMsghdr {
+			length := int(nativeEndian.Uint32(data[0:4]))
+			if length < unix.SizeofNlMsghdr || length > len(data) {
+				return nil, fmt.Errorf("Received a malformed netlink message")
+			}
+			replyType := nativeEndian.Uint16(data[4:6])
+			replySeq := nativeEndian.Uint32(data[8:12])
+			body := data[unix.SizeofNlMsghdr:length]
+			data = data[nlmAlign(length):]
+
+			if replySeq != seq {
+				continue
+			}
+
+			switch replyType {
+			case unix.NLMSG_DONE:
+				return replies, nil
+			case unix.NLMSG_ERROR:
+				if len(body) < 4 {
+					return nil, fmt.Errorf("Received a malformed netlink error")
+				}
+				if errno := int32(nativeEndian.Uint32(body[0:4])); errno != 0 {
+					return nil, unix.Errno(-errno)
+				}
+				// Acknowledgement of a request that is not a dump
+				if flags&unix.NLM_F_DUMP == 0 {
+					return replies, nil
+				}
+			default:
+				message := netlinkMessage{Type: replyType, Data: make([]byte, len(body))}
+				copy(message.Data, body)
+				replies = append(replies, message)
+			}
+		}
+	}
+}
+
+func nlmAlign(length int) int {
+	return (length + unix.NLA_ALIGNTO - 1) & ^(unix.NLA_ALIGNTO - 1)
+}
+
+func encodeAttr(attrType uint16, data []byte) []byte {
+	length := unix.SizeofRtAttr + len(data)
+	attr := make([]byte, nlmAlign(length))
+	nativeEndian.PutUint16(attr[0:2], uint16(length))
+	nativeEndian.PutUint16(attr[2:4], attrType)
+	copy(attr[unix.SizeofRtAttr:], data)
+	return attr
+}
+
+func encodeStringAttr(attrType uint16, value string) []byte {
+	return encodeAttr(attrType, append([]byte(value), 0))
+}
+
+func parseAttrs(data []byte) []netlinkAttr {
+	var attrs []netlinkAttr
+
+	for len(data) >= unix.SizeofRtAttr {
+		length := int(nativeEndian.Uint16(data[0:2]))
+		if length < unix.SizeofRtAttr || length > len(data) {
+			break
+		}
+		attrs = append(attrs, netlinkAttr{
+			Type: nativeEndian.Uint16(data[2:4]) & ^uint16(unix.NLA_F_NESTED),
+			Data: data[unix.SizeofRtAttr:length],
+		})
+
+		if nlmAlign(length) >= len(data) {
+			break
+		}
+		data = data[nlmAlign(length):]
+	}
+
+	return attrs
+}
+
+func attrString(data []byte) string {
+	for i, b := range data {
+		if b == 0 {
+			return string(data[:i])
+		}
+	}
+	return string(data)
+}
+
+func attrUint32(data []byte) uint32 {
+	if len(data) < 4 {
+		return 0
+	}
+	return nativeEndian.Uint32(data)
+}
@@ -4,6 +4,9 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -12,17 +15,12 @@ type Network struct {
 	Unit      *Unit
 }
 
-var intfNetwork map[string]*Network
-
-func init() {
-	intfNetwork = make(map[string]*Network)
-}
-
 func (self *Network) UpdateNetDev(netdev *NetDev) error {
 	dropinUnit, err := self.DropinForNetDev(netdev)
 	if err != nil {
 		return err
 	}
+	touchLink(self.Interface.Name)
 
 	switch netdev.Status {
 	case LinkDisabled:
@@ -30,9 +28,14 @@ func (self *Network) UpdateNetDev(netdev *NetDev) error {
 			return fmt.Errorf("Failed to remove parent unit %s: %w",
 				dropinUnit.Path, err)
 		}
-	case LinkEnabled:
-		dropinUnit.File.NewSection("Network")
-		dropinUnit.File.Section("Network").NewKey("VLAN", netdev.Name)
+	case LinkEnabled, LinkEnabledRuntime:
+		// Only attaching needs the key, so links of unknown kinds can
+		// still be detached
+		key, err := attachKey(netdev.Kind)
+		if err != nil {
+			return err
+		}
+		dropinUnit.File.Section("Network").Set(key, netdev.Name)
 		if err := dropinUnit.Save(); err != nil {
 			return fmt.Errorf("Failed to update parent unit %s: %w",
 				dropinUnit.Path, err)
@@ -43,29 +46,115 @@ func (self *Network) UpdateNetDev(netdev *NetDev) error {
 }
 
 func (self *Network) DropinForNetDev(netdev *NetDev) (*Unit, error) {
-	if self.Interface.NetIf == nil {
-		if self.Interface.Name != "" {
-			return nil, fmt.Errorf("There is no interface \"%s\" for link %s (%s)",
-				self.Interface.Name, netdev.Name, netdev.Unit.Name)
-		}
+	if self.Interface.Name == "" {
 		return nil, fmt.Errorf(
 			"Unable to determine the parent interface for link %s (%s)",
 			netdev.Name, netdev.Unit.Name)
 	}
 
+	if !self.Interface.present() {
+		return nil, fmt.Errorf("There is no interface \"%s\" for link %s (%s)",
+			self.Interface.Name, netdev.Name, netdev.Unit.Name)
+	}
+
 	if self.Unit == nil {
 		return nil, fmt.Errorf("Unable to determine network unit for interface %s",
 			self.Interface.Name)
 	}
 
 	dropinName := strings.TrimSuffix(netdev.Unit.Name, ".netdev")
+	if netdev.Runtime {
+		return self.Unit.newDropinIn(options.RuntimeDir, dropinName)
+	}
 	return self.Unit.NewDropin(dropinName)
 }
 
-func getNetworkUnit(intf *Interface) (*Unit, error) {
-	var networkFile string
+// networkUnits returns the network units systemd-networkd reads, with the
+// same shadowing and masking rules as netdevs.
+func networkUnits() ([]string, error) {
+	var units []string
+	found := make(map[string]bool)
+	for _, dir := range options.searchDirs() {
+		path := filepath.Join(options.Path(dir), "*.network")
+		files, err := glob(path)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to list units at %s: %w", path, err)
+		}
+
+		for _, file := range files {
+			name := filepath.Base(file)
+			if found[name] {
+				continue
+			}
+			found[name] = true
+			if !isMasked(file) {
+				units = append(units, file)
+			}
+		}
+	}
 
-	stateFile := fmt.Sprintf("/run/systemd/netif/links/%d", intf.NetIf.Index)
+	sort.SliceStable(units, func(i, j int) bool {
+		return filepath.Base(units[i]) < filepath.Base(units[j])
+	})
+	return units, nil
+}
+
+// matchNetworkUnit returns the first network unit, in file name order,
+// whose [Match] Name= patterns match the interface. It stands in for the
+// state files of the running system when operating on another root, so
+// units that do not match by name are not considered.
+func matchNetworkUnit(intfName string) (*Unit, error) {
+	paths, err := networkUnits()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range paths {
+		unit, err := NewUnit(path)
+		if err != nil {
+			return nil, err
+		}
+
+		effective, err := unit.Effective()
+		if err != nil {
+			return nil, err
+		}
+
+		if matchName(effective.Values("Match", "Name"), intfName) {
+			return unit, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// matchName reports whether a Name= list matches an interface name. A list
+// starting with '!' is inverted.
+func matchName(patterns []string, name string) bool {
+	if len(patterns) == 0 {
+		return false
+	}
+
+	invert := strings.HasPrefix(patterns[0], "!")
+	if invert {
+		patterns = append([]string{strings.TrimPrefix(patterns[0], "!")}, patterns[1:]...)
+	}
+
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return !invert
+		}
+	}
+	return invert
+}
+
+// readLinkState parses the state file systemd-networkd keeps for a link
+func readLinkState(index int) (map[string]string, error) {
+	state := make(map[string]string)
+
+	stateFile := filepath.Join(
+		options.Path(options.StateDir),
+		strconv.Itoa(index))
 	file, err := os.Open(stateFile)
 	if err != nil {
 		return nil, err
@@ -75,40 +164,40 @@ func getNetworkUnit(intf *Interface) (*Unit, error) {
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
 		line := scanner.Text()
-		if !strings.HasPrefix(line, "NETWORK_FILE") {
+		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
 		stateParts := strings.SplitN(line, "=", 2)
 		if len(stateParts) != 2 {
-			return nil, fmt.Errorf("Unable to parse state file for %d", intf.NetIf.Index)
+			return nil, &os.PathError{Op: "parse", Path: stateFile, Err: fmt.Errorf("invalid line %s", line)}
 		}
-		networkFile = stateParts[1]
+		state[stateParts[0]] = stateParts[1]
 	}
 
 	if err := scanner.Err(); err != nil {
 		return nil, err
 	}
 
-	unit, err := NewUnit(networkFile)
-	return unit, err
+	return state, nil
 }
 
-func NetworkFromIntf(intfName string) *Network {
-	if intfName == "" {
-		return nil
+func getNetworkUnit(intf *Interface) (*Unit, error) {
+	state, err := readLinkState(intf.NetIf.Index)
+	if err != nil {
+		return nil, err
 	}
 
-	if network, ok := intfNetwork[intfName]; ok {
-		return network
+	networkFile := state["NETWORK_FILE"]
+	if networkFile != "" {
+		networkFile = options.Path(networkFile)
 	}
 
-	var newNetwork Network
-
-	newNetwork.Interface = NewInterface(intfName)
-	if newNetwork.Interface.NetIf != nil {
-		newNetwork.Unit, _ = getNetworkUnit(newNetwork.Interface)
-	}
+	unit, err := NewUnit(networkFile)
+	return unit, err
+}
 
-	intfNetwork[intfName] = &newNetwork
-	return intfNetwork[intfName]
+// NetworkFromIntf returns the network of an interface from the default
+// inventory
+func NetworkFromIntf(intfName string) *Network {
+	return defaultInventory.Network(intfName)
 }
@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"golang.org/x/sys/unix"
 	"os"
-	"os/exec"
+	"strings"
 	"sync"
 	"time"
 )
@@ -20,7 +20,7 @@ func animateProgress(step int) {
 	if progress < 0 {
 		progress = -progress
 	}
-	fmt.Fprintf(os.Stderr, "\rWaiting on systemd-network to restart [%*s%*s]", progress+1, "=", 5-progress, "")
+	fmt.Fprintf(os.Stderr, "\rWaiting on systemd-networkd to reload [%*s%*s]", progress+1, "=", 5-progress, "")
 }
 
 func progressMessage(ctx context.Context, wg *sync.WaitGroup) {
@@ -38,7 +38,7 @@ func progressMessage(ctx context.Context, wg *sync.WaitGroup) {
 			if animate {
 				animateProgress(count)
 			} else if !endLine {
-				fmt.Print("Waiting on systemd-networkd to restart...")
+				fmt.Print("Waiting on systemd-networkd to reload...")
 			}
 			endLine = true
 			count++
@@ -51,15 +51,34 @@ func progressMessage(ctx context.Context, wg *sync.WaitGroup) {
 	}
 }
 
-func Restart() error {
-	cmd := exec.Command("systemctl", "restart", "systemd-networkd")
-	waitGroup := sync.WaitGroup{}
-	ctx, cancel := context.WithCancel(context.Background())
-	waitGroup.Add(1)
+// Reload asks systemd-networkd to reload its configuration and reconfigure
+// the given links, leaving every other link untouched. D-Bus is used when
+// available, otherwise networkctl.
+func Reload(links ...string) error {
+	return ReloadContext(context.Background(), links...)
+}
 
-	go progressMessage(ctx, &waitGroup)
+func ReloadContext(ctx context.Context, links ...string) error {
+	if plan != nil {
+		plan.record(&Change{Type: ChangeReload, Path: strings.Join(links, " ")})
+		return nil
+	}
+
+	if !options.IsLive() {
+		return nil
+	}
 
-	err := cmd.Run()
+	waitGroup := sync.WaitGroup{}
+	progressCtx, cancel := context.WithCancel(ctx)
+	if !options.Quiet {
+		waitGroup.Add(1)
+		go progressMessage(progressCtx, &waitGroup)
+	}
+
+	err := reloadDBus(ctx, links)
+	if isNoBus(err) {
+		err = reloadNetworkctl(ctx, links)
+	}
 	cancel()
 	waitGroup.Wait()
 
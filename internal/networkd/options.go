@@ -0,0 +1,152 @@
+package networkd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Options describes where linkctl finds and writes systemd-networkd
+// configuration. Every directory is interpreted relative to Root, which
+// allows operating on an image chroot, a staging tree or a test fixture
+// without touching the live system.
+type Options struct {
+	// Root is prepended to every other path. An empty Root refers to the
+	// live system.
+	Root string
+
+	// NetworkDir is where systemd-networkd reads enabled units and drop-ins.
+	NetworkDir string
+
+	// RuntimeDir and VendorDir are also searched by systemd-networkd, after
+	// NetworkDir, for units generated at runtime or shipped by packages.
+	RuntimeDir string
+	VendorDir  string
+
+	// UserDir, SystemDir and AvailableDir hold the netdevs available to be
+	// enabled, in order of precedence.
+	UserDir      string
+	SystemDir    string
+	AvailableDir string
+
+	// StateDir holds the per-link state files written by systemd-networkd.
+	StateDir string
+
+	// KeyDir holds the private keys of WireGuard links created by linkctl.
+	KeyDir string
+
+	// Quiet suppresses the progress message printed while waiting on
+	// systemd-networkd to reload.
+	Quiet bool
+}
+
+var DefaultOptions = Options{
+	NetworkDir:   "/etc/systemd/network",
+	RuntimeDir:   "/run/systemd/network",
+	VendorDir:    "/usr/lib/systemd/network",
+	UserDir:      "/etc/linkctl/user",
+	SystemDir:    "/etc/linkctl/system",
+	AvailableDir: "/etc/systemd/network/netdev.available",
+	StateDir:     "/run/systemd/netif/links",
+	KeyDir:       "/etc/linkctl/keys",
+}
+
+var options = DefaultOptions
+
+// Configure replaces the active options and discards any cached links.
+// Directories left empty fall back to DefaultOptions.
+func Configure(opts Options) {
+	if opts.NetworkDir == "" {
+		opts.NetworkDir = DefaultOptions.NetworkDir
+	}
+	if opts.RuntimeDir == "" {
+		opts.RuntimeDir = DefaultOptions.RuntimeDir
+	}
+	if opts.VendorDir == "" {
+		opts.VendorDir = DefaultOptions.VendorDir
+	}
+	if opts.UserDir == "" {
+		opts.UserDir = DefaultOptions.UserDir
+	}
+	if opts.SystemDir == "" {
+		opts.SystemDir = DefaultOptions.SystemDir
+	}
+	if opts.AvailableDir == "" {
+		opts.AvailableDir = DefaultOptions.AvailableDir
+	}
+	if opts.StateDir == "" {
+		opts.StateDir = DefaultOptions.StateDir
+	}
+	if opts.KeyDir == "" {
+		opts.KeyDir = DefaultOptions.KeyDir
+	}
+
+	options = opts
+	invalidateInventories()
+}
+
+// searchDirs returns the directories systemd-networkd reads units and
+// drop-ins from, in order of precedence.
+func (self Options) searchDirs() []string {
+	return []string{self.NetworkDir, self.RuntimeDir, self.VendorDir}
+}
+
+// NetDevDirs returns every directory netdevs are discovered in, in order
+// of precedence. A link defined in one directory overrides definitions of
+// the same name in the directories after it.
+func NetDevDirs() []string {
+	return append(options.searchDirs(), options.UserDir, options.SystemDir, options.AvailableDir)
+}
+
+// IsLive reports whether the options refer to the running system
+func (self Options) IsLive() bool {
+	return self.Root == "" || self.Root == "/"
+}
+
+// Path returns the location of a system path beneath Root
+func (self Options) Path(path string) string {
+	if self.IsLive() {
+		return path
+	}
+	return filepath.Join(self.Root, path)
+}
+
+// SystemPath strips Root from a path, returning the location the running
+// system will see, e.g. as the target of a symlink.
+func (self Options) SystemPath(path string) string {
+	if self.IsLive() {
+		return path
+	}
+
+	root := filepath.Clean(self.Root)
+	if path == root {
+		return "/"
+	}
+	if strings.HasPrefix(path, root+"/") {
+		return strings.TrimPrefix(path, root)
+	}
+	return path
+}
+
+// SystemPath strips the configured root from a path
+func SystemPath(path string) string {
+	return options.SystemPath(path)
+}
+
+// resolve follows an absolute symlink within Root so units enabled inside
+// a chroot are read from the chroot rather than the host.
+func (self Options) resolve(path string) string {
+	if self.IsLive() {
+		return path
+	}
+
+	for i := 0; i < 8; i++ {
+		target, err := os.Readlink(path)
+		if err != nil || !filepath.IsAbs(target) {
+			return path
+		}
+		path = self.Path(target)
+	}
+
+	return path
+}
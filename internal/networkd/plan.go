@@ -0,0 +1,353 @@
+package networkd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+type ChangeType string
+
+const (
+	ChangeWrite      ChangeType = "write"
+	ChangeSymlink    ChangeType = "symlink"
+	ChangeRemove     ChangeType = "remove"
+	ChangeDeleteLink ChangeType = "delete-link"
+	ChangeReload     ChangeType = "reload"
+)
+
+// Change is a single modification linkctl makes to the system
+type Change struct {
+	Type ChangeType
+	// Path is the file being changed, the link name for ChangeDeleteLink
+	// or the links being reconfigured for ChangeReload
+	Path string
+	// Target is the destination of a symlink
+	Target string
+}
+
+// Plan records the changes linkctl would make instead of applying them.
+// Files written by the plan are visible to later reads so that a command
+// sees the same state it would have seen had the changes been applied.
+type Plan struct {
+	Changes  []*Change
+	files    map[string]*planFile
+	original map[string]*planFile
+}
+
+type planFile struct {
+	exists bool
+	data   []byte
+	target string
+	secret bool
+}
+
+var plan *Plan
+
+// DryRun starts recording changes in a plan rather than applying them
+func DryRun() *Plan {
+	plan = &Plan{
+		files:    make(map[string]*planFile),
+		original: make(map[string]*planFile),
+	}
+	return plan
+}
+
+func (self *Plan) record(change *Change) {
+	self.Changes = append(self.Changes, change)
+}
+
+func (self *Plan) stage(path string, file *planFile) {
+	if _, ok := self.original[path]; !ok {
+		self.original[path] = diskFile(path)
+	}
+	self.files[path] = file
+}
+
+func diskFile(path string) *planFile {
+	if target, err := os.Readlink(path); err == nil {
+		return &planFile{exists: true, target: target}
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return &planFile{}
+	}
+	return &planFile{exists: true, data: data}
+}
+
+// content returns the text of a planned file, following symlinks
+func (self *Plan) content(file *planFile) []byte {
+	if !file.exists {
+		return nil
+	}
+	if file.target == "" {
+		return file.data
+	}
+
+	data, err := readFile(options.Path(file.target))
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// WriteDiff prints the plan as a unified diff of every file it touches,
+// interleaved with the commands that would be run.
+func (self *Plan) WriteDiff(w io.Writer) error {
+	shown := make(map[string]bool)
+
+	for _, change := range self.Changes {
+		var err error
+		switch change.Type {
+		case ChangeDeleteLink:
+			_, err = fmt.Fprintf(w, "# ip link del %s\n", change.Path)
+		case ChangeReload:
+			_, err = fmt.Fprintln(w, "# networkctl reload")
+			if err == nil && change.Path != "" {
+				_, err = fmt.Fprintf(w, "# networkctl reconfigure %s\n", change.Path)
+			}
+		default:
+			if shown[change.Path] {
+				continue
+			}
+			shown[change.Path] = true
+			err = self.writeFileDiff(w, change.Path)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (self *Plan) writeFileDiff(w io.Writer, path string) error {
+	before := self.original[path]
+	after := self.files[path]
+
+	fromName := options.SystemPath(path)
+	toName := fromName
+	if !before.exists {
+		fromName = "/dev/null"
+	}
+	if !after.exists {
+		toName = "/dev/null"
+	}
+
+	if after.secret {
+		_, err := fmt.Fprintf(w, "# write %s (contents hidden)\n", toName)
+		return err
+	}
+
+	if before.target != after.target {
+		if before.target != "" {
+			if _, err := fmt.Fprintf(w, "# remove symlink %s -> %s\n", options.SystemPath(path), before.target); err != nil {
+				return err
+			}
+		}
+		if after.target != "" {
+			if _, err := fmt.Fprintf(w, "# symlink %s -> %s\n", options.SystemPath(path), after.target); err != nil {
+				return err
+			}
+		}
+	}
+
+	return unifiedDiff(w, fromName, toName,
+		self.content(before), self.content(after))
+}
+
+func writeFile(path string, data []byte) error {
+	if plan != nil {
+		plan.stage(path, &planFile{exists: true, data: data})
+		plan.record(&Change{Type: ChangeWrite, Path: path})
+		return nil
+	}
+
+	if transaction != nil {
+		transaction.snapshot(path)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// writeSecret writes a file that only root and systemd-networkd may read
+func writeSecret(path string, data []byte) error {
+	if plan != nil {
+		plan.stage(path, &planFile{exists: true, data: data, secret: true})
+		plan.record(&Change{Type: ChangeWrite, Path: path})
+		return nil
+	}
+
+	if transaction != nil {
+		transaction.snapshot(path)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// systemd-networkd runs as systemd-network and must be able to read
+	// the file, everyone else is denied
+	if group, err := user.LookupGroup("systemd-network"); err == nil {
+		if gid, err := strconv.Atoi(group.Gid); err == nil {
+			if err := file.Chown(0, gid); err != nil {
+				return err
+			}
+			if err := file.Chmod(0640); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = file.Write(data)
+	return err
+}
+
+func symlink(target string, path string) error {
+	if plan != nil {
+		if fileExists(path) {
+			return &os.LinkError{Op: "symlink", Old: target, New: path, Err: os.ErrExist}
+		}
+		plan.stage(path, &planFile{exists: true, target: target})
+		plan.record(&Change{Type: ChangeSymlink, Path: path, Target: target})
+		return nil
+	}
+
+	// Never snapshot a path that is in the way, restoring it on rollback
+	// would remove it
+	if fileExists(path) {
+		return &os.LinkError{Op: "symlink", Old: target, New: path, Err: os.ErrExist}
+	}
+
+	if transaction != nil {
+		transaction.snapshot(path)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return os.Symlink(target, path)
+}
+
+func removeFile(path string) error {
+	if plan != nil {
+		if !fileExists(path) {
+			return &os.PathError{Op: "remove", Path: path, Err: os.ErrNotExist}
+		}
+		plan.stage(path, &planFile{})
+		plan.record(&Change{Type: ChangeRemove, Path: path})
+		return nil
+	}
+
+	if transaction != nil {
+		transaction.snapshot(path)
+	}
+
+	return os.Remove(path)
+}
+
+func removeEmptyDir(path string) {
+	if plan != nil {
+		return
+	}
+
+	if dirIsEmpty(path) {
+		os.Remove(path)
+	}
+}
+
+func readFile(path string) ([]byte, error) {
+	if plan != nil {
+		if file, ok := plan.files[path]; ok {
+			if !file.exists {
+				return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+			}
+			if file.target != "" {
+				return readFile(options.Path(file.target))
+			}
+			return file.data, nil
+		}
+	}
+
+	return ioutil.ReadFile(options.resolve(path))
+}
+
+func fileExists(path string) bool {
+	if plan != nil {
+		if file, ok := plan.files[path]; ok {
+			return file.exists
+		}
+	}
+
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// isMasked reports whether a unit is masked by a symlink to /dev/null or an
+// empty file
+func isMasked(path string) bool {
+	if plan != nil {
+		if file, ok := plan.files[path]; ok {
+			return file.exists && (file.target == "/dev/null" ||
+				file.target == "" && len(file.data) == 0)
+		}
+	}
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		return false
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		return err == nil && target == "/dev/null"
+	}
+
+	return info.Mode().IsRegular() && info.Size() == 0
+}
+
+func glob(pattern string) ([]string, error) {
+	matches, err := filepath.Glob(pattern)
+	if err != nil || plan == nil {
+		return matches, err
+	}
+
+	found := make(map[string]bool)
+	for _, match := range matches {
+		found[match] = true
+	}
+
+	for path, file := range plan.files {
+		if ok, _ := filepath.Match(pattern, path); ok {
+			found[path] = file.exists
+		}
+	}
+
+	matches = matches[:0]
+	for path, exists := range found {
+		if exists {
+			matches = append(matches, path)
+		}
+	}
+	sort.Strings(matches)
+
+	return matches, nil
+}
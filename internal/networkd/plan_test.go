@@ -0,0 +1,193 @@
+package networkd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// treeContents returns every file and symlink beneath root with its
+// contents or target
+func treeContents(t *testing.T, root string) map[string]string {
+	t.Helper()
+
+	tree := make(map[string]string)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(path)
+			tree[path] = "-> " + target
+			return err
+		}
+
+		data, err := os.ReadFile(path)
+		tree[path] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+func TestDryRun(t *testing.T) {
+	const (
+		network   = "etc/systemd/network/10-eth0.network"
+		available = "etc/linkctl/system/50-eth0.v300.netdev"
+		enabled   = "etc/systemd/network/50-eth0.v300.netdev"
+		parent    = "etc/systemd/network/10-eth0.network.d/50-eth0.v300.conf"
+	)
+
+	tests := []struct {
+		name        string
+		files       map[string]string
+		change      func(netdev *NetDev) error
+		wantChanges []ChangeType
+		wantDiff    []string
+	}{
+		{
+			name:   "enable",
+			files:  map[string]string{available: vlanUnit("v300", "300")},
+			change: (*NetDev).Enable,
+			wantChanges: []ChangeType{
+				ChangeWrite, ChangeSymlink, ChangeReload,
+			},
+			wantDiff: []string{
+				"+++ /" + parent + "\n",
+				"+VLAN=v300\n",
+				"# symlink /" + enabled + " -> /" + available + "\n",
+				"# networkctl reload\n# networkctl reconfigure v300 eth0\n",
+			},
+		},
+		{
+			name: "disable",
+			files: map[string]string{
+				available: vlanUnit("v300", "300"),
+				enabled:   "-> /" + available,
+				parent:    "[Network]\nVLAN=v300\n",
+			},
+			change: (*NetDev).Disable,
+			wantChanges: []ChangeType{
+				ChangeRemove, ChangeRemove, ChangeReload,
+			},
+			wantDiff: []string{
+				"--- /" + parent + "\n+++ /dev/null\n",
+				"-VLAN=v300\n",
+				"# remove symlink /" + enabled + " -> /" + available + "\n",
+				"# networkctl reload\n# networkctl reconfigure eth0\n",
+			},
+		},
+		{
+			name: "rename",
+			files: map[string]string{
+				available: vlanUnit("v300", "300"),
+			},
+			change: func(netdev *NetDev) error {
+				return netdev.Rename("storage")
+			},
+			wantChanges: []ChangeType{ChangeWrite, ChangeReload},
+			wantDiff: []string{
+				"--- /dev/null\n+++ /" + enabled + ".d/name.conf\n",
+				"+Name=storage\n",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.files[network] = "[Match]\nName=eth0\n"
+			root := newTestRoot(t, test.files)
+			before := treeContents(t, root)
+
+			netdev, ok := GetNetDev("v300")
+			if !ok {
+				t.Fatalf("link v300 not found, problems: %v", Problems())
+			}
+
+			dryRun := DryRun()
+			if err := Apply(func() error { return test.change(netdev) }); err != nil {
+				t.Fatal(err)
+			}
+
+			if after := treeContents(t, root); !reflect.DeepEqual(after, before) {
+				t.Errorf("dry run changed the root:\n%v\nwant\n%v", after, before)
+			}
+
+			var changes []ChangeType
+			for _, change := range dryRun.Changes {
+				changes = append(changes, change.Type)
+			}
+			if !reflect.DeepEqual(changes, test.wantChanges) {
+				t.Errorf("changes = %v, want %v", changes, test.wantChanges)
+			}
+
+			var diff bytes.Buffer
+			if err := dryRun.WriteDiff(&diff); err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range test.wantDiff {
+				if !strings.Contains(diff.String(), want) {
+					t.Errorf("diff does not contain %q:\n%s", want, diff.String())
+				}
+			}
+		})
+	}
+}
+
+func TestDryRunReadsPlannedFiles(t *testing.T) {
+	const path = "etc/systemd/network/10-eth0.network"
+
+	root := newTestRoot(t, map[string]string{path: "[Match]\nName=eth0\n"})
+	fullPath := filepath.Join(root, path)
+	DryRun()
+
+	if err := writeFile(fullPath, []byte("planned")); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := readFile(fullPath); err != nil || string(data) != "planned" {
+		t.Errorf("readFile = %q, %v, want the planned contents", data, err)
+	}
+
+	if err := removeFile(fullPath); err != nil {
+		t.Fatal(err)
+	}
+	if fileExists(fullPath) {
+		t.Error("planned removal still exists")
+	}
+	if err := removeFile(fullPath); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("second removal error = %v, want %v", err, os.ErrNotExist)
+	}
+
+	if err := symlink("/dev/null", fullPath); err != nil {
+		t.Fatal(err)
+	}
+	if !isMasked(fullPath) {
+		t.Error("planned symlink to /dev/null is not masked")
+	}
+}
+
+func TestSymlinkExists(t *testing.T) {
+	const path = "etc/systemd/network/50-eth0.v300.netdev"
+
+	for _, dryRun := range []bool{false, true} {
+		root := newTestRoot(t, map[string]string{path: vlanUnit("v300", "300")})
+		if dryRun {
+			DryRun()
+		}
+
+		err := symlink("/dev/null", filepath.Join(root, path))
+		if !errors.Is(err, os.ErrExist) {
+			t.Errorf("dry run %v: symlink error = %v, want %v", dryRun, err, os.ErrExist)
+		}
+		if got := readTestFile(t, root, path); got != vlanUnit("v300", "300") {
+			t.Errorf("dry run %v: %s = %q after a failed symlink", dryRun, path, got)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package networkd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// vlanUnit defines a vlan netdev for tests
+func vlanUnit(name string, id string) string {
+	return "[NetDev]\nName=" + name + "\nKind=vlan\n\n[VLAN]\nId=" + id + "\n"
+}
+
+// newTestRoot writes files beneath a temporary root and configures the
+// package to operate on it. A value starting with "-> " is written as a
+// symlink to the rest of the value.
+func newTestRoot(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	root := t.TempDir()
+	for path, content := range files {
+		fullPath := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		var err error
+		if target := strings.TrimPrefix(content, "-> "); target != content {
+			err = os.Symlink(target, fullPath)
+		} else {
+			err = os.WriteFile(fullPath, []byte(content), 0644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	Configure(Options{Root: root})
+	t.Cleanup(func() {
+		plan = nil
+		transaction = nil
+		Configure(Options{})
+	})
+
+	return root
+}
+
+// checkFiles fails the test for every path whose existence beneath root
+// differs from want
+func checkFiles(t *testing.T, root string, want map[string]bool) {
+	t.Helper()
+
+	for path, exists := range want {
+		_, err := os.Lstat(filepath.Join(root, path))
+		if exists && err != nil {
+			t.Errorf("%s is missing: %v", path, err)
+		} else if !exists && err == nil {
+			t.Errorf("%s exists, want it removed", path)
+		}
+	}
+}
+
+// readTestFile returns the contents of a file beneath root, or "" if it
+// does not exist
+func readTestFile(t *testing.T, root string, path string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(root, path))
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	return string(data)
+}
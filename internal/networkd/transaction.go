@@ -0,0 +1,286 @@
+package networkd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// Transaction snapshots every file linkctl changes while it is active so
+// the system can be returned to its previous state if a later step fails.
+// Deleted links are not recreated directly; systemd-networkd brings them
+// back when it reloads the restored configuration.
+type Transaction struct {
+	snapshots []*snapshot
+	touched   map[string]bool
+	links     []string
+}
+
+type snapshot struct {
+	path string
+	file *planFile
+	mode os.FileMode
+	// uid and gid are the owner of the file, e.g. systemd-network for a
+	// WireGuard key, -1 if unknown
+	uid        int
+	gid        int
+	createsDir bool
+}
+
+var transaction *Transaction
+
+// Begin starts recording snapshots of every file modified until the
+// transaction is committed or rolled back.
+func Begin() *Transaction {
+	transaction = &Transaction{
+		touched: make(map[string]bool),
+	}
+	return transaction
+}
+
+// Apply runs fn and reloads systemd-networkd inside a transaction. Only
+// the links touched by fn are reconfigured. If fn or the reload fails
+// every file change is rolled back.
+func Apply(fn func() error) error {
+	return ApplyContext(context.Background(), fn)
+}
+
+// ApplyContext is Apply with a context that bounds the reload. Nothing is
+// changed if the context is already done.
+func ApplyContext(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	tx := Begin()
+
+	if err := fn(); err != nil {
+		return tx.Rollback(err)
+	}
+
+	if err := ReloadContext(ctx, tx.links...); err != nil {
+		return tx.Rollback(fmt.Errorf("Failed to reload systemd-networkd: %w", err))
+	}
+
+	tx.Commit()
+	return nil
+}
+
+// Result is the outcome of a change to a single link
+type Result struct {
+	Name string
+	Err  error
+}
+
+// ApplyEach runs fn for every netdev and reloads systemd-networkd once. A
+// netdev whose change fails has only its own changes rolled back. If the
+// reload fails every change is rolled back.
+func ApplyEach(links []*NetDev, fn func(netdev *NetDev) error) ([]Result, error) {
+	tx := Begin()
+
+	var results []Result
+	changed := false
+	for _, netdev := range links {
+		savepoint := tx.savepoint()
+		err := fn(netdev)
+		if err != nil {
+			if restoreErr := tx.rollbackTo(savepoint); restoreErr != nil {
+				err = fmt.Errorf("%w\nFailed to roll back changes: %v", err, restoreErr)
+			}
+		} else {
+			changed = true
+		}
+		results = append(results, Result{Name: netdev.Name, Err: err})
+	}
+
+	if !changed {
+		tx.Commit()
+		return results, nil
+	}
+
+	if err := Reload(tx.links...); err != nil {
+		return results, tx.Rollback(fmt.Errorf("Failed to reload systemd-networkd: %w", err))
+	}
+
+	tx.Commit()
+	return results, nil
+}
+
+// savepoint marks the state of a transaction, and of the plan during a dry
+// run, so later changes can be undone on their own
+type savepoint struct {
+	snapshots int
+	links     int
+	changes   int
+	files     map[string]*planFile
+}
+
+func (self *Transaction) savepoint() *savepoint {
+	point := savepoint{
+		snapshots: len(self.snapshots),
+		links:     len(self.links),
+	}
+
+	if plan != nil {
+		point.changes = len(plan.Changes)
+		point.files = make(map[string]*planFile, len(plan.files))
+		for path, file := range plan.files {
+			point.files[path] = file
+		}
+	}
+
+	return &point
+}
+
+// rollbackTo restores every file changed since the savepoint. Netdevs
+// changed since then may no longer match their files, so every inventory
+// is reloaded on next use.
+func (self *Transaction) rollbackTo(point *savepoint) error {
+	invalidateInventories()
+
+	if plan != nil {
+		plan.Changes = plan.Changes[:point.changes]
+		plan.files = point.files
+	}
+
+	var failed []string
+	for i := len(self.snapshots) - 1; i >= point.snapshots; i-- {
+		snap := self.snapshots[i]
+		if err := snap.restore(); err != nil {
+			failed = append(failed, err.Error())
+		}
+		delete(self.touched, snap.path)
+	}
+	self.snapshots = self.snapshots[:point.snapshots]
+	self.links = self.links[:point.links]
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%v", failed)
+	}
+	return nil
+}
+
+// touchLink marks a link as needing to be reconfigured when the active
+// transaction is applied.
+func touchLink(name string) {
+	if transaction == nil || name == "" {
+		return
+	}
+
+	for _, link := range transaction.links {
+		if link == name {
+			return
+		}
+	}
+	transaction.links = append(transaction.links, name)
+}
+
+func (self *Transaction) snapshot(path string) {
+	if self.touched[path] {
+		return
+	}
+	self.touched[path] = true
+
+	snap := snapshot{
+		path: path,
+		file: diskFile(path),
+		uid:  -1,
+		gid:  -1,
+	}
+	if info, err := os.Lstat(path); err == nil {
+		snap.mode = info.Mode().Perm()
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+			snap.uid = int(stat.Uid)
+			snap.gid = int(stat.Gid)
+		}
+	}
+	if _, err := os.Stat(filepath.Dir(path)); os.IsNotExist(err) {
+		snap.createsDir = true
+	}
+
+	self.snapshots = append(self.snapshots, &snap)
+}
+
+// Commit ends the transaction, keeping every change
+func (self *Transaction) Commit() {
+	if transaction == self {
+		transaction = nil
+	}
+}
+
+// Rollback restores every file touched by the transaction, reloads
+// systemd-networkd and returns cause annotated with the outcome.
+func (self *Transaction) Rollback(cause error) error {
+	self.Commit()
+
+	// Loaded links reflect the failed changes
+	invalidateInventories()
+
+	if len(self.snapshots) == 0 {
+		return cause
+	}
+
+	var failed []string
+	for i := len(self.snapshots) - 1; i >= 0; i-- {
+		if err := self.snapshots[i].restore(); err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%w\nFailed to roll back changes: %v", cause, failed)
+	}
+
+	if err := Reload(self.links...); err != nil {
+		return fmt.Errorf("%w\nChanges were rolled back but systemd-networkd failed to reload: %v", cause, err)
+	}
+
+	return fmt.Errorf("%w\nAll changes have been rolled back", cause)
+}
+
+// restoreFile writes the snapshotted contents with their original owner
+// and mode. The file is only readable by its creator until both are set.
+func (self *snapshot) restoreFile() error {
+	file, err := os.OpenFile(self.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if self.uid >= 0 {
+		if err := file.Chown(self.uid, self.gid); err != nil {
+			return err
+		}
+	}
+	if err := file.Chmod(self.mode); err != nil {
+		return err
+	}
+
+	_, err = file.Write(self.file.data)
+	return err
+}
+
+func (self *snapshot) restore() error {
+	if err := os.Remove(self.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if self.file.exists {
+		if err := os.MkdirAll(filepath.Dir(self.path), os.ModePerm); err != nil {
+			return err
+		}
+
+		if self.file.target != "" {
+			return os.Symlink(self.file.target, self.path)
+		}
+		return self.restoreFile()
+	}
+
+	if self.createsDir {
+		removeEmptyDir(filepath.Dir(self.path))
+	}
+
+	return nil
+}
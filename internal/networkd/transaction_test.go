@@ -0,0 +1,211 @@
+package networkd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplyRollback(t *testing.T) {
+	const (
+		existing = "etc/systemd/network/10-eth0.network"
+		created  = "etc/systemd/network/10-eth0.network.d/50-eth0.v300.conf"
+		linked   = "etc/systemd/network/50-eth0.v300.netdev"
+		source   = "etc/linkctl/system/50-eth0.v300.netdev"
+		original = "[Match]\nName=eth0\n"
+	)
+
+	tests := []struct {
+		name   string
+		change func(root string) error
+	}{
+		{
+			name: "write",
+			change: func(root string) error {
+				return writeFile(filepath.Join(root, existing), []byte("[Match]\nName=eth1\n"))
+			},
+		},
+		{
+			name: "chmod and write",
+			change: func(root string) error {
+				if err := writeFile(filepath.Join(root, existing), []byte("")); err != nil {
+					return err
+				}
+				return os.Chmod(filepath.Join(root, existing), 0600)
+			},
+		},
+		{
+			name: "remove",
+			change: func(root string) error {
+				return removeFile(filepath.Join(root, existing))
+			},
+		},
+		{
+			name: "create in a new directory",
+			change: func(root string) error {
+				return writeFile(filepath.Join(root, created), []byte("[Network]\nVLAN=v300\n"))
+			},
+		},
+		{
+			name: "symlink",
+			change: func(root string) error {
+				return symlink("/"+source, filepath.Join(root, linked))
+			},
+		},
+		{
+			name: "write twice",
+			change: func(root string) error {
+				path := filepath.Join(root, existing)
+				if err := writeFile(path, []byte("first")); err != nil {
+					return err
+				}
+				return writeFile(path, []byte("second"))
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := newTestRoot(t, map[string]string{
+				existing: original,
+				source:   vlanUnit("v300", "300"),
+			})
+
+			failure := errors.New("Failed")
+			err := Apply(func() error {
+				if err := test.change(root); err != nil {
+					t.Fatal(err)
+				}
+				return failure
+			})
+			if !errors.Is(err, failure) {
+				t.Fatalf("Apply error = %v, want %v", err, failure)
+			}
+			if transaction != nil {
+				t.Error("transaction still active after rollback")
+			}
+
+			if got := readTestFile(t, root, existing); got != original {
+				t.Errorf("%s = %q, want %q", existing, got, original)
+			}
+			if info, err := os.Stat(filepath.Join(root, existing)); err != nil {
+				t.Error(err)
+			} else if info.Mode().Perm() != 0644 {
+				t.Errorf("%s mode = %v, want 0644", existing, info.Mode().Perm())
+			}
+			checkFiles(t, root, map[string]bool{
+				created:                false,
+				filepath.Dir(created):  false,
+				linked:                 false,
+				source:                 true,
+				filepath.Dir(existing): true,
+			})
+		})
+	}
+}
+
+func TestApplyCommit(t *testing.T) {
+	const path = "etc/systemd/network/10-eth0.network"
+
+	root := newTestRoot(t, map[string]string{path: "[Match]\nName=eth0\n"})
+
+	want := "[Match]\nName=eth1\n"
+	err := Apply(func() error {
+		return writeFile(filepath.Join(root, path), []byte(want))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readTestFile(t, root, path); got != want {
+		t.Errorf("%s = %q, want %q", path, got, want)
+	}
+}
+
+func TestApplyEach(t *testing.T) {
+	const (
+		network  = "etc/systemd/network/10-eth0.network"
+		v300     = "etc/systemd/network/50-eth0.v300.netdev"
+		v301     = "etc/systemd/network/50-eth0.v301.netdev"
+		dropin   = "etc/systemd/network/10-eth0.network.d/50-eth0.v301.conf"
+		v300Conf = "etc/systemd/network/10-eth0.network.d/50-eth0.v300.conf"
+	)
+
+	tests := []struct {
+		name     string
+		fail     map[string]bool
+		wantErr  map[string]bool
+		wantLink map[string]LinkStatus
+		want     map[string]bool
+	}{
+		{
+			name:     "all succeed",
+			wantErr:  map[string]bool{},
+			wantLink: map[string]LinkStatus{"v300": LinkEnabled, "v301": LinkEnabled},
+			want:     map[string]bool{v300: true, v301: true, v300Conf: true, dropin: true},
+		},
+		{
+			name:     "one fails",
+			fail:     map[string]bool{"v301": true},
+			wantErr:  map[string]bool{"v301": true},
+			wantLink: map[string]LinkStatus{"v300": LinkEnabled, "v301": LinkDisabled},
+			want:     map[string]bool{v300: true, v300Conf: true, v301: false, dropin: false},
+		},
+		{
+			name:     "all fail",
+			fail:     map[string]bool{"v300": true, "v301": true},
+			wantErr:  map[string]bool{"v300": true, "v301": true},
+			wantLink: map[string]LinkStatus{"v300": LinkDisabled, "v301": LinkDisabled},
+			want:     map[string]bool{v300: false, v300Conf: false, v301: false, dropin: false},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := newTestRoot(t, map[string]string{
+				network:                                  "[Match]\nName=eth0\n",
+				"etc/linkctl/system/50-eth0.v300.netdev": vlanUnit("v300", "300"),
+				"etc/linkctl/system/50-eth0.v301.netdev": vlanUnit("v301", "301"),
+			})
+
+			var links []*NetDev
+			for _, name := range []string{"v300", "v301"} {
+				netdev, ok := GetNetDev(name)
+				if !ok {
+					t.Fatalf("link %s not found, problems: %v", name, Problems())
+				}
+				links = append(links, netdev)
+			}
+
+			results, err := ApplyEach(links, func(netdev *NetDev) error {
+				if err := netdev.Enable(); err != nil {
+					return err
+				}
+				if test.fail[netdev.Name] {
+					return errors.New("Failed")
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, result := range results {
+				if (result.Err != nil) != test.wantErr[result.Name] {
+					t.Errorf("%s error = %v, want error %v", result.Name, result.Err, test.wantErr[result.Name])
+				}
+			}
+			checkFiles(t, root, test.want)
+
+			// Rolled back links must be reloaded rather than keep the
+			// status set by the failed change
+			for name, status := range test.wantLink {
+				if netdev, ok := GetNetDev(name); !ok {
+					t.Errorf("link %s not found", name)
+				} else if netdev.Status != status {
+					t.Errorf("%s status = %s, want %s", name, netdev.Status, status)
+				}
+			}
+		})
+	}
+}
@@ -5,27 +5,37 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
-
-	"gopkg.in/ini.v1"
 )
 
+// UnitError is a unit file that could not be parsed
+type UnitError struct {
+	Path string
+	Err  error
+}
+
+func (self *UnitError) Error() string {
+	return fmt.Sprintf("Failed to parse unit %s: %v", self.Path, self.Err)
+}
+
+func (self *UnitError) Unwrap() error {
+	return self.Err
+}
+
 type Unit struct {
 	Path string
 	Name string
-	File *ini.File
+	File *UnitFile
 }
 
 func (self *Unit) Delete() error {
-	err := os.Remove(self.Path)
+	err := removeFile(self.Path)
 	if err != nil && !os.IsNotExist(err) {
 		return err
 	}
 
-	unitDir := filepath.Dir(self.Path)
-	if dirIsEmpty(unitDir) {
-		os.Remove(unitDir)
-	}
+	removeEmptyDir(filepath.Dir(self.Path))
 	return nil
 }
 
@@ -38,36 +48,11 @@ func (self *Unit) DeleteIfEmpty() error {
 }
 
 func (self *Unit) IsEmpty() bool {
-	for _, section := range self.File.Sections() {
-		if !SectionEmpty(section) {
-			return false
-		}
-	}
-
-	return true
+	return self.File.IsEmpty()
 }
 
 func (self *Unit) Save() error {
-	err := os.MkdirAll(filepath.Dir(self.Path), os.ModePerm)
-	if err != nil {
-		return err
-	}
-
-	return self.File.SaveTo(self.Path)
-}
-
-func SectionEmpty(section *ini.Section) bool {
-	if len(section.Keys()) > 0 {
-		return false
-	}
-
-	for _, child := range section.ChildSections() {
-		if !SectionEmpty(child) {
-			return false
-		}
-	}
-
-	return true
+	return writeFile(self.Path, self.File.Bytes())
 }
 
 func dirIsEmpty(path string) bool {
@@ -84,54 +69,87 @@ func NewUnit(path string) (*Unit, error) {
 		Name: filepath.Base(path),
 	}
 
-	iniFile, err := ini.Load(path)
-	if err != nil {
-		if !os.IsNotExist(err) {
-			return nil, err
+	unitFile := NewUnitFile()
+	data, err := readFile(path)
+	if err == nil {
+		unitFile, err = ParseUnitFile(data)
+		if err != nil {
+			return nil, &UnitError{Path: path, Err: err}
 		}
-
-		iniFile = ini.Empty()
 	}
-	unit.File = iniFile
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	unit.File = unitFile
 
 	return &unit, nil
 }
 
 func (self *Unit) NewDropin(name string) (*Unit, error) {
-	dropinPath := fmt.Sprintf(
-		"/etc/systemd/network/%s.d/%s.conf",
-		self.Name,
-		name)
-
-	return NewUnit(dropinPath)
+	return self.newDropinIn(options.NetworkDir, name)
 }
 
-func (self *Unit) Dropins() []string {
-	dropinPath := fmt.Sprintf("/etc/systemd/network/%s.d/*.conf", self.Name)
-	dropins, err := filepath.Glob(dropinPath)
-	if err != nil {
-		panic(fmt.Errorf("Failed to list units at %s: %w", dropinPath, err))
-	}
+func (self *Unit) newDropinIn(dir string, name string) (*Unit, error) {
+	dropinPath := filepath.Join(
+		options.Path(dir),
+		self.Name+".d",
+		name+".conf")
 
-	return dropins
+	return NewUnit(dropinPath)
 }
 
-func (self *Unit) DropinUnits() chan *Unit {
-	r := make(chan *Unit, 5)
+// Dropins returns the drop-ins of the unit from every networkd search
+// directory in the order systemd applies them: sorted by file name, with a
+// drop-in shadowing those of the same name in lower precedence directories.
+func (self *Unit) Dropins() ([]string, error) {
+	var dropins []string
+	found := make(map[string]bool)
+
+	for _, dir := range options.searchDirs() {
+		dropinPath := filepath.Join(options.Path(dir), self.Name+".d", "*.conf")
+		matches, err := glob(dropinPath)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to list units at %s: %w", dropinPath, err)
+		}
 
-	go func() {
-		for _, dropin := range self.Dropins() {
-			unit, err := NewUnit(dropin)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "WARN: Failed to parse networkd unit %s: %v\n", dropin, err)
+		for _, match := range matches {
+			name := filepath.Base(match)
+			if found[name] {
 				continue
 			}
-			r <- unit
+			found[name] = true
+
+			// A masked drop-in hides those of the same name without
+			// contributing any configuration
+			if !isMasked(match) {
+				dropins = append(dropins, match)
+			}
 		}
-		close(r)
-	}()
+	}
+
+	sort.SliceStable(dropins, func(i, j int) bool {
+		return filepath.Base(dropins[i]) < filepath.Base(dropins[j])
+	})
+
+	return dropins, nil
+}
+
+// DropinUnits parses the drop-ins of the unit in the order they apply
+func (self *Unit) DropinUnits() ([]*Unit, error) {
+	paths, err := self.Dropins()
+	if err != nil {
+		return nil, err
+	}
 
-	return r
+	var units []*Unit
+	for _, path := range paths {
+		unit, err := NewUnit(path)
+		if err != nil {
+			return nil, err
+		}
+		units = append(units, unit)
+	}
+	return units, nil
 }
 
 func (self *Unit) ContainsValue(section string, key string, value string) bool {
@@ -149,38 +167,55 @@ func (self *Unit) Set(section string, key string, value string) error {
 		return self.Remove(section, key)
 	}
 
-	s := self.File.Section(section)
-	s.NewKey(key, value)
+	// Earlier sections of the same name would otherwise still assign key
+	target := self.File.Section(section)
+	for _, s := range self.File.SectionsByName(section) {
+		if s != target {
+			s.Delete(key)
+		}
+	}
+	target.Set(key, value)
+
 	return self.Save()
 }
 
 func (self *Unit) Get(section string, key string) string {
-	s := self.File.Section(section)
-	if s == nil || !s.HasKey(key) {
-		return ""
-	}
-
-	return s.Key(key).String()
+	return self.File.Get(section, key)
 }
 
+// GetValues returns the words of a list setting. Repeated assignments
+// extend the list and an empty assignment resets it.
 func (self *Unit) GetValues(section string, key string) []string {
-	if value := self.Get(section, key); value != "" {
-		return strings.Split(self.Get(section, key), " ")
+	values := []string{}
+	for _, value := range self.File.Values(section, key) {
+		if value == "" {
+			values = []string{}
+			continue
+		}
+		values = append(values, splitWords(value)...)
 	}
-	return []string{}
+	return values
 }
 
 func (self *Unit) SetValues(section string, key string, values []string) error {
-	return self.Set(section, key, strings.Join(values, " "))
+	words := make([]string, 0, len(values))
+	for _, value := range values {
+		if value != "" {
+			words = append(words, quoteWord(value))
+		}
+	}
+	return self.Set(section, key, strings.Join(words, " "))
 }
 
 func (self *Unit) Remove(section string, key string) error {
-	s := self.File.Section(section)
-	if s == nil {
+	sections := self.File.SectionsByName(section)
+	if len(sections) == 0 {
 		return nil
 	}
 
-	s.DeleteKey(key)
+	for _, s := range sections {
+		s.Delete(key)
+	}
 
 	// Delete the file if no more config exists
 	if self.IsEmpty() {
@@ -196,7 +231,6 @@ func (self *Unit) Remove(section string, key string) error {
 
 func (self *Unit) Replace(section string, key string, old string, value string) error {
 	values := self.GetValues(section, key)
-	fmt.Printf("Setting value from %v\n", values)
 	update := make([]string, len(values)+1)
 	kept := 0
 	for _, v := range values {
@@ -205,9 +239,7 @@ func (self *Unit) Replace(section string, key string, old string, value string)
 			kept += 1
 		}
 	}
-	fmt.Printf("Setting value after %v with |%s|\n", update, value)
 	update[kept] = value
-	fmt.Printf("Setting value to %v\n", update)
 	return self.SetValues(section, key, update)
 }
 
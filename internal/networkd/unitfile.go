@@ -0,0 +1,447 @@
+package networkd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// UnitFile is a systemd unit file parsed with the rules systemd-networkd
+// uses. Unlike an ini file, sections such as [Address], [Route] or
+// [WireGuardPeer] may be repeated and keys may be assigned more than once.
+// Comments, blank lines, line continuations and quoting are preserved so
+// a file is written back exactly as it was read, less any changes.
+type UnitFile struct {
+	// header holds the comments and blank lines before the first section
+	header   []string
+	sections []*UnitSection
+	// warnings describe lines that systemd-networkd ignores
+	warnings []string
+}
+
+// UnitSection is a single [Section] of a unit file
+type UnitSection struct {
+	Name    string
+	raw     string
+	entries []*unitEntry
+}
+
+// unitEntry is an assignment, or a comment or blank line when Key is empty
+type unitEntry struct {
+	Key   string
+	Value string
+	// raw holds the lines the entry was read from, it is nil for entries
+	// added or changed since the file was parsed
+	raw []string
+}
+
+// NewUnitFile returns an empty unit file
+func NewUnitFile() *UnitFile {
+	return &UnitFile{}
+}
+
+// ParseUnitFile parses the contents of a unit file
+func ParseUnitFile(data []byte) (*UnitFile, error) {
+	file := NewUnitFile()
+	var section *UnitSection
+	var entry *unitEntry
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 4096), 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		lineNumber++
+
+		// A continued assignment absorbs the following lines, comments
+		// between them are ignored as systemd does
+		if entry != nil {
+			entry.raw = append(entry.raw, line)
+			trimmed := strings.TrimSpace(line)
+			if isComment(trimmed) {
+				continue
+			}
+			value, continued := continuation(trimmed)
+			entry.Value = joinContinuation(entry.Value, value)
+			if !continued {
+				entry.Value = strings.TrimSpace(entry.Value)
+				entry = nil
+			}
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "" || isComment(trimmed):
+			if section == nil {
+				file.header = append(file.header, line)
+			} else {
+				section.entries = append(section.entries, &unitEntry{raw: []string{line}})
+			}
+
+		case strings.HasPrefix(trimmed, "["):
+			if !strings.HasSuffix(trimmed, "]") || len(trimmed) < 3 {
+				return nil, fmt.Errorf("line %d: invalid section header %s", lineNumber, trimmed)
+			}
+			section = &UnitSection{
+				Name: trimmed[1 : len(trimmed)-1],
+				raw:  line,
+			}
+			file.sections = append(file.sections, section)
+
+		// Lines systemd-networkd warns about and ignores are kept as they
+		// are so they are written back unchanged
+		case section == nil:
+			file.warnings = append(file.warnings,
+				fmt.Sprintf("line %d: assignment outside of a section, ignoring %s", lineNumber, trimmed))
+			file.header = append(file.header, line)
+
+		case strings.IndexByte(trimmed, '=') < 1:
+			file.warnings = append(file.warnings,
+				fmt.Sprintf("line %d: missing '=', ignoring %s", lineNumber, trimmed))
+			section.entries = append(section.entries, &unitEntry{raw: []string{line}})
+
+		default:
+			equals := strings.IndexByte(trimmed, '=')
+			value, continued := continuation(strings.TrimSpace(trimmed[equals+1:]))
+			current := &unitEntry{
+				Key:   strings.TrimSpace(trimmed[:equals]),
+				Value: value,
+				raw:   []string{line},
+			}
+			section.entries = append(section.entries, current)
+			if continued {
+				entry = current
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if entry != nil {
+		entry.Value = strings.TrimSpace(entry.Value)
+	}
+
+	return file, nil
+}
+
+// Warnings describes the lines systemd-networkd ignores, such as a line
+// without an assignment
+func (self *UnitFile) Warnings() []string {
+	return self.warnings
+}
+
+func isComment(line string) bool {
+	return strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";")
+}
+
+// continuation strips the backslash from a line that continues on the next
+func continuation(line string) (string, bool) {
+	if strings.HasSuffix(line, "\\") {
+		return strings.TrimRight(strings.TrimSuffix(line, "\\"), " \t"), true
+	}
+	return line, false
+}
+
+func joinContinuation(value string, line string) string {
+	if value == "" {
+		return line
+	}
+	return value + " " + line
+}
+
+// Bytes serializes the unit file
+func (self *UnitFile) Bytes() []byte {
+	var buf bytes.Buffer
+	self.WriteTo(&buf)
+	return buf.Bytes()
+}
+
+// WriteTo writes the unit file to w, entries that have not changed are
+// written exactly as they were read.
+func (self *UnitFile) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+
+	for _, line := range self.header {
+		buf.WriteString(line + "\n")
+	}
+
+	for i, section := range self.sections {
+		// Separate a new section from the one before it
+		if section.raw == "" && (i > 0 || len(self.header) > 0) && !endsWithBlank(buf.Bytes()) {
+			buf.WriteString("\n")
+		}
+
+		if section.raw != "" {
+			buf.WriteString(section.raw + "\n")
+		} else {
+			buf.WriteString("[" + section.Name + "]\n")
+		}
+
+		for _, entry := range section.entries {
+			if entry.raw != nil {
+				for _, line := range entry.raw {
+					buf.WriteString(line + "\n")
+				}
+				continue
+			}
+			buf.WriteString(entry.Key + "=" + entry.Value + "\n")
+		}
+	}
+
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+func endsWithBlank(data []byte) bool {
+	return len(data) == 0 || bytes.HasSuffix(data, []byte("\n\n"))
+}
+
+// Sections returns every section in the order they appear
+func (self *UnitFile) Sections() []*UnitSection {
+	return self.sections
+}
+
+// SectionsByName returns every section with the given name
+func (self *UnitFile) SectionsByName(name string) []*UnitSection {
+	var sections []*UnitSection
+	for _, section := range self.sections {
+		if section.Name == name {
+			sections = append(sections, section)
+		}
+	}
+	return sections
+}
+
+// GetSection returns the last section with the given name, or nil
+func (self *UnitFile) GetSection(name string) *UnitSection {
+	for i := len(self.sections) - 1; i >= 0; i-- {
+		if self.sections[i].Name == name {
+			return self.sections[i]
+		}
+	}
+	return nil
+}
+
+// Section returns the last section with the given name, adding it to the
+// end of the file if it does not exist.
+func (self *UnitFile) Section(name string) *UnitSection {
+	if section := self.GetSection(name); section != nil {
+		return section
+	}
+	return self.NewSection(name)
+}
+
+// NewSection appends a section to the file, even if one with the same name
+// already exists.
+func (self *UnitFile) NewSection(name string) *UnitSection {
+	section := &UnitSection{Name: name}
+	self.sections = append(self.sections, section)
+	return section
+}
+
+// DeleteSection removes a section from the file
+func (self *UnitFile) DeleteSection(section *UnitSection) {
+	for i, s := range self.sections {
+		if s == section {
+			self.sections = append(self.sections[:i], self.sections[i+1:]...)
+			return
+		}
+	}
+}
+
+// Get returns the last value assigned to key in any section with the given
+// name, later assignments override earlier ones.
+func (self *UnitFile) Get(section string, key string) string {
+	value := ""
+	for _, s := range self.SectionsByName(section) {
+		if s.HasKey(key) {
+			value = s.Get(key)
+		}
+	}
+	return value
+}
+
+// Values returns every value assigned to key in sections with the given
+// name, in order.
+func (self *UnitFile) Values(section string, key string) []string {
+	var values []string
+	for _, s := range self.SectionsByName(section) {
+		values = append(values, s.Values(key)...)
+	}
+	return values
+}
+
+// IsEmpty reports whether the file has no assignments
+func (self *UnitFile) IsEmpty() bool {
+	for _, section := range self.sections {
+		if !section.IsEmpty() {
+			return false
+		}
+	}
+	return true
+}
+
+// Keys returns the keys assigned in the section, in order and without
+// duplicates.
+func (self *UnitSection) Keys() []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, entry := range self.entries {
+		if entry.Key != "" && !seen[entry.Key] {
+			seen[entry.Key] = true
+			keys = append(keys, entry.Key)
+		}
+	}
+	return keys
+}
+
+// HasKey reports whether key is assigned in the section
+func (self *UnitSection) HasKey(key string) bool {
+	for _, entry := range self.entries {
+		if entry.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the last value assigned to key, or "" if it is not assigned
+func (self *UnitSection) Get(key string) string {
+	values := self.Values(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+// Values returns every value assigned to key, in order
+func (self *UnitSection) Values(key string) []string {
+	var values []string
+	for _, entry := range self.entries {
+		if entry.Key == key {
+			values = append(values, entry.Value)
+		}
+	}
+	return values
+}
+
+// Set replaces every assignment of key with a single assignment of value,
+// keeping the position of the first.
+func (self *UnitSection) Set(key string, value string) {
+	kept := self.entries[:0]
+	replaced := false
+	for _, entry := range self.entries {
+		if entry.Key != key {
+			kept = append(kept, entry)
+			continue
+		}
+		if !replaced {
+			if entry.Value != value || len(entry.raw) > 1 {
+				entry.Value = value
+				entry.raw = nil
+			}
+			kept = append(kept, entry)
+			replaced = true
+		}
+	}
+	self.entries = kept
+
+	if !replaced {
+		self.Add(key, value)
+	}
+}
+
+// Add appends an assignment of key, keeping any existing assignments
+func (self *UnitSection) Add(key string, value string) {
+	entry := &unitEntry{Key: key, Value: value}
+
+	// Keep trailing comments and blank lines at the end of the section
+	i := len(self.entries)
+	for i > 0 && self.entries[i-1].Key == "" {
+		i--
+	}
+	self.entries = append(self.entries, nil)
+	copy(self.entries[i+1:], self.entries[i:])
+	self.entries[i] = entry
+}
+
+// Delete removes every assignment of key
+func (self *UnitSection) Delete(key string) {
+	kept := self.entries[:0]
+	for _, entry := range self.entries {
+		if entry.Key != key {
+			kept = append(kept, entry)
+		}
+	}
+	self.entries = kept
+}
+
+// IsEmpty reports whether the section has no assignments
+func (self *UnitSection) IsEmpty() bool {
+	for _, entry := range self.entries {
+		if entry.Key != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// splitWords splits a value into words separated by whitespace. Single and
+// double quotes group words and a backslash escapes the next character.
+func splitWords(value string) []string {
+	var words []string
+	var word strings.Builder
+	var quote rune
+	inWord := false
+	escaped := false
+
+	for _, r := range value {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if inWord {
+		words = append(words, word.String())
+	}
+
+	return words
+}
+
+// quoteWord quotes a word that would otherwise be split by splitWords
+func quoteWord(word string) string {
+	if word != "" && !strings.ContainsAny(word, " \t\n\"'\\") {
+		return word
+	}
+
+	replacer := strings.NewReplacer("\\", "\\\\", "\"", "\\\"")
+	return "\"" + replacer.Replace(word) + "\""
+}
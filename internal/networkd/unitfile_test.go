@@ -0,0 +1,324 @@
+package networkd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnitFileRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{
+			name: "empty",
+			data: "",
+		},
+		{
+			name: "simple",
+			data: "[NetDev]\nName=test.300\nKind=vlan\n\n[VLAN]\nId=300\n",
+		},
+		{
+			name: "comments",
+			data: "# header comment\n\n[NetDev]\n; semicolon comment\nName=test.300 # not a comment\n  # indented\n",
+		},
+		{
+			name: "whitespace",
+			data: "[NetDev]\n  Name =  test.300  \n\tKind=vlan\n\n\n",
+		},
+		{
+			name: "continuation",
+			data: "[Network]\nAddress=10.0.0.1/24 \\\n  10.0.0.2/24 \\\n# ignored\n  10.0.0.3/24\nDHCP=yes\n",
+		},
+		{
+			name: "repeated sections",
+			data: "[WireGuard]\nPrivateKeyFile=/etc/linkctl/keys/wg0.key\n\n" +
+				"[WireGuardPeer]\nPublicKey=a\nAllowedIPs=10.0.0.0/24\n\n" +
+				"[WireGuardPeer]\nPublicKey=b\nAllowedIPs=10.0.1.0/24\nAllowedIPs=10.0.2.0/24\n",
+		},
+		{
+			name: "quoting",
+			data: "[Match]\nName=\"eth 0\" 'eth 1' eth\\ 2\n",
+		},
+		{
+			name: "empty assignment",
+			data: "[Network]\nVLAN=test.300\nVLAN=\nVLAN=test.301\n",
+		},
+		{
+			name: "ignored lines",
+			data: "Name=outside\n[NetDev]\nOops\nName=test.300\n",
+		},
+		{
+			name: "no trailing newline",
+			data: "[NetDev]\nName=test.300",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file, err := ParseUnitFile([]byte(test.data))
+			if err != nil {
+				t.Fatalf("ParseUnitFile: %v", err)
+			}
+
+			want := test.data
+			if want != "" && want[len(want)-1] != '\n' {
+				want += "\n"
+			}
+			if got := string(file.Bytes()); got != want {
+				t.Errorf("round trip = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestParseUnitFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		section  string
+		key      string
+		values   []string
+		sections int
+		warnings int
+	}{
+		{
+			name:     "trimmed",
+			data:     "[NetDev]\n  Name =  test.300  \n",
+			section:  "NetDev",
+			key:      "Name",
+			values:   []string{"test.300"},
+			sections: 1,
+		},
+		{
+			name:     "continuation",
+			data:     "[Network]\nAddress=10.0.0.1/24 \\\n  10.0.0.2/24 \\\n# ignored\n  10.0.0.3/24\n",
+			section:  "Network",
+			key:      "Address",
+			values:   []string{"10.0.0.1/24 10.0.0.2/24 10.0.0.3/24"},
+			sections: 1,
+		},
+		{
+			name:     "continuation at end of file",
+			data:     "[Network]\nAddress=10.0.0.1/24 \\",
+			section:  "Network",
+			key:      "Address",
+			values:   []string{"10.0.0.1/24"},
+			sections: 1,
+		},
+		{
+			name:     "repeated sections",
+			data:     "[WireGuardPeer]\nPublicKey=a\n[WireGuardPeer]\nPublicKey=b\n",
+			section:  "WireGuardPeer",
+			key:      "PublicKey",
+			values:   []string{"a", "b"},
+			sections: 2,
+		},
+		{
+			name:     "repeated keys",
+			data:     "[Network]\nVLAN=test.300\nVLAN=\nVLAN=test.301\n",
+			section:  "Network",
+			key:      "VLAN",
+			values:   []string{"test.300", "", "test.301"},
+			sections: 1,
+		},
+		{
+			name:     "ignored lines",
+			data:     "Name=outside\n[NetDev]\nOops\n=value\nName=test.300\n",
+			section:  "NetDev",
+			key:      "Name",
+			values:   []string{"test.300"},
+			sections: 1,
+			warnings: 3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file, err := ParseUnitFile([]byte(test.data))
+			if err != nil {
+				t.Fatalf("ParseUnitFile: %v", err)
+			}
+
+			if got := len(file.Sections()); got != test.sections {
+				t.Errorf("sections = %d, want %d", got, test.sections)
+			}
+			if got := file.Values(test.section, test.key); !reflect.DeepEqual(got, test.values) {
+				t.Errorf("Values(%s, %s) = %q, want %q", test.section, test.key, got, test.values)
+			}
+			if got := len(file.Warnings()); got != test.warnings {
+				t.Errorf("warnings = %q, want %d", file.Warnings(), test.warnings)
+			}
+		})
+	}
+}
+
+func TestParseUnitFileInvalidSection(t *testing.T) {
+	for _, data := range []string{"[NetDev\nName=a\n", "[]\n"} {
+		if _, err := ParseUnitFile([]byte(data)); err == nil {
+			t.Errorf("ParseUnitFile(%q) succeeded, want an error", data)
+		}
+	}
+}
+
+func TestUnitSectionEdit(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		edit func(file *UnitFile)
+		want string
+	}{
+		{
+			name: "set keeps position",
+			data: "[NetDev]\nName=a\nKind=vlan\n",
+			edit: func(file *UnitFile) { file.Section("NetDev").Set("Name", "b") },
+			want: "[NetDev]\nName=b\nKind=vlan\n",
+		},
+		{
+			name: "set same value keeps formatting",
+			data: "[NetDev]\nName = a # comment\n",
+			edit: func(file *UnitFile) { file.Section("NetDev").Set("Name", "a # comment") },
+			want: "[NetDev]\nName = a # comment\n",
+		},
+		{
+			name: "set replaces repeated keys",
+			data: "[Network]\nVLAN=a\nDHCP=yes\nVLAN=b\n",
+			edit: func(file *UnitFile) { file.Section("Network").Set("VLAN", "c") },
+			want: "[Network]\nVLAN=c\nDHCP=yes\n",
+		},
+		{
+			name: "add before trailing comments",
+			data: "[Network]\nVLAN=a\n# trailing\n\n",
+			edit: func(file *UnitFile) { file.Section("Network").Add("VLAN", "b") },
+			want: "[Network]\nVLAN=a\nVLAN=b\n# trailing\n\n",
+		},
+		{
+			name: "delete",
+			data: "[Network]\nVLAN=a\nDHCP=yes\nVLAN=b\n",
+			edit: func(file *UnitFile) { file.Section("Network").Delete("VLAN") },
+			want: "[Network]\nDHCP=yes\n",
+		},
+		{
+			name: "new section",
+			data: "[NetDev]\nName=a\n",
+			edit: func(file *UnitFile) { file.Section("VLAN").Set("Id", "300") },
+			want: "[NetDev]\nName=a\n\n[VLAN]\nId=300\n",
+		},
+		{
+			name: "delete repeated section",
+			data: "[WireGuardPeer]\nPublicKey=a\n\n[WireGuardPeer]\nPublicKey=b\n",
+			edit: func(file *UnitFile) { file.DeleteSection(file.SectionsByName("WireGuardPeer")[0]) },
+			want: "[WireGuardPeer]\nPublicKey=b\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file, err := ParseUnitFile([]byte(test.data))
+			if err != nil {
+				t.Fatalf("ParseUnitFile: %v", err)
+			}
+
+			test.edit(file)
+			if got := string(file.Bytes()); got != test.want {
+				t.Errorf("edited = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"eth0", []string{"eth0"}},
+		{"eth0  eth1\teth2", []string{"eth0", "eth1", "eth2"}},
+		{`"eth 0" 'eth 1'`, []string{"eth 0", "eth 1"}},
+		{`eth\ 0 eth\\1`, []string{"eth 0", `eth\1`}},
+		{`"say \"hi\""`, []string{`say "hi"`}},
+		{`a"b c"d`, []string{"ab cd"}},
+		{`""`, []string{""}},
+	}
+
+	for _, test := range tests {
+		if got := splitWords(test.value); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("splitWords(%q) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
+
+func TestQuoteWord(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"eth0", "eth0"},
+		{"", `""`},
+		{"eth 0", `"eth 0"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{`back\slash`, `"back\\slash"`},
+		{"it's", `"it's"`},
+	}
+
+	for _, test := range tests {
+		got := quoteWord(test.word)
+		if got != test.want {
+			t.Errorf("quoteWord(%q) = %q, want %q", test.word, got, test.want)
+		}
+
+		if words := splitWords(got); !reflect.DeepEqual(words, []string{test.word}) {
+			t.Errorf("splitWords(quoteWord(%q)) = %q", test.word, words)
+		}
+	}
+}
+
+func TestGetValues(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{
+			name: "unset",
+			data: "[Network]\nDHCP=yes\n",
+			want: []string{},
+		},
+		{
+			name: "extended",
+			data: "[Network]\nVLAN=a b\nVLAN=c\n",
+			want: []string{"a", "b", "c"},
+		},
+		{
+			name: "reset",
+			data: "[Network]\nVLAN=a b\nVLAN=\nVLAN=c\n",
+			want: []string{"c"},
+		},
+		{
+			name: "reset last",
+			data: "[Network]\nVLAN=a\nVLAN=\n",
+			want: []string{},
+		},
+		{
+			name: "repeated sections",
+			data: "[Network]\nVLAN=a\n[Network]\nVLAN=\"b c\"\n",
+			want: []string{"a", "b c"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file, err := ParseUnitFile([]byte(test.data))
+			if err != nil {
+				t.Fatalf("ParseUnitFile: %v", err)
+			}
+
+			unit := &Unit{Name: "10-eth0.network", File: file}
+			if got := unit.GetValues("Network", "VLAN"); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("GetValues = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
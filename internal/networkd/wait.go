@@ -0,0 +1,92 @@
+package networkd
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const waitInterval = 250 * time.Millisecond
+
+// String describes the state in the same terms as networkctl
+func (self *LinkState) String() string {
+	if self == nil {
+		return "absent"
+	}
+	return fmt.Sprintf("%s (%s)", self.OperationalState, self.AdministrativeState)
+}
+
+// IsReady reports whether networkd finished configuring the link or the
+// link reached a usable operational state.
+func (self *LinkState) IsReady() bool {
+	if self == nil {
+		return false
+	}
+
+	switch self.OperationalState {
+	case "routable", "carrier":
+		return true
+	}
+	return self.AdministrativeState == "configured"
+}
+
+// IsFailed reports whether networkd gave up configuring the link
+func (self *LinkState) IsFailed() bool {
+	return self != nil && self.AdministrativeState == "failed"
+}
+
+func readLinkStateByName(name string) (*LinkState, error) {
+	intf, err := NewInterface(name)
+	if err != nil || intf.NetIf == nil {
+		return nil, err
+	}
+
+	values, err := readLinkState(intf.NetIf.Index)
+	if err != nil {
+		return &LinkState{OperationalState: "unknown", AdministrativeState: "pending"}, nil
+	}
+
+	return newLinkState(values), nil
+}
+
+func newLinkState(values map[string]string) *LinkState {
+	return &LinkState{
+		OperationalState:    values["OPER_STATE"],
+		AdministrativeState: values["ADMIN_STATE"],
+		CarrierState:        values["CARRIER_STATE"],
+		AddressState:        values["ADDRESS_STATE"],
+	}
+}
+
+// WaitForLink polls the state file systemd-networkd keeps for a link until
+// the link is ready, networkd fails to configure it, or ctx is done. The
+// last state seen is always returned.
+func WaitForLink(ctx context.Context, name string) (*LinkState, error) {
+	// Links are not created when operating on another root or in a plan
+	if !options.IsLive() || plan != nil {
+		return nil, nil
+	}
+
+	ticker := time.NewTicker(waitInterval)
+	defer ticker.Stop()
+
+	for {
+		state, err := readLinkStateByName(name)
+		if err != nil {
+			return nil, err
+		}
+		if state.IsReady() {
+			return state, nil
+		}
+		if state.IsFailed() {
+			return state, fmt.Errorf("systemd-networkd failed to configure link %s", name)
+		}
+
+		select {
+		case <-ctx.Done():
+			return state, fmt.Errorf("Timed out waiting for link %s, last state %s",
+				name, state)
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package networkd
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Size of the generic netlink header: command, version and reserved
+const genlHeaderLen = 4
+
+// WireGuardDevice is the kernel's view of a wireguard link
+type WireGuardDevice struct {
+	Name       string
+	PublicKey  string
+	ListenPort int
+	Peers      []*WireGuardPeerState
+}
+
+// WireGuardPeerState is the kernel's view of a peer of a wireguard link
+type WireGuardPeerState struct {
+	PublicKey           string
+	Endpoint            string
+	AllowedIPs          []string
+	PersistentKeepalive int
+	// LastHandshake is zero if the peer has never completed a handshake
+	LastHandshake time.Time
+	RxBytes       uint64
+	TxBytes       uint64
+}
+
+// Peer returns the state of the peer with the given public key, or nil if
+// the kernel does not know it.
+func (self *WireGuardDevice) Peer(publicKey string) *WireGuardPeerState {
+	for _, peer := range self.Peers {
+		if peer.PublicKey == publicKey {
+			return peer
+		}
+	}
+	return nil
+}
+
+// GetWireGuardDevice queries the kernel for the state of a wireguard link
+func GetWireGuardDevice(name string) (*WireGuardDevice, error) {
+	conn, err := dialNetlink(unix.NETLINK_GENERIC)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	family, err := genlFamily(conn, unix.WG_GENL_NAME)
+	if errors.Is(err, unix.ENOENT) {
+		return nil, fmt.Errorf("The wireguard kernel module is not loaded")
+	} else if err != nil {
+		return nil, err
+	}
+
+	request := []byte{unix.WG_CMD_GET_DEVICE, unix.WG_GENL_VERSION, 0, 0}
+	request = append(request, encodeStringAttr(unix.WGDEVICE_A_IFNAME, name)...)
+
+	replies, err := conn.Execute(family, unix.NLM_F_DUMP, request)
+	if errors.Is(err, unix.ENODEV) {
+		return nil, fmt.Errorf("The link %s does not exist", name)
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Devices with many peers are split across several messages, each
+	// repeating the device attributes
+	device := WireGuardDevice{Name: name}
+	for _, reply := range replies {
+		if reply.Type != family || len(reply.Data) < genlHeaderLen {
+			continue
+		}
+		parseWireGuardDevice(&device, reply.Data[genlHeaderLen:])
+	}
+
+	return &device, nil
+}
+
+// genlFamily resolves the id of a generic netlink family
+func genlFamily(conn *netlinkConn, name string) (uint16, error) {
+	request := []byte{unix.CTRL_CMD_GETFAMILY, 1, 0, 0}
+	request = append(request, encodeStringAttr(unix.CTRL_ATTR_FAMILY_NAME, name)...)
+
+	replies, err := conn.Execute(unix.GENL_ID_CTRL, 0, request)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, reply := range replies {
+		if reply.Type != unix.GENL_ID_CTRL || len(reply.Data) < genlHeaderLen {
+			continue
+		}
+		for _, attr := range parseAttrs(reply.Data[genlHeaderLen:]) {
+			if attr.Type == unix.CTRL_ATTR_FAMILY_ID && len(attr.Data) >= 2 {
+				return nativeEndian.Uint16(attr.Data), nil
+			}
+		}
+	}
+
+	return 0, unix.ENOENT
+}
+
+func parseWireGuardDevice(device *WireGuardDevice, data []byte) {
+	for _, attr := range parseAttrs(data) {
+		switch attr.Type {
+		case unix.WGDEVICE_A_PUBLIC_KEY:
+			device.PublicKey = base64.StdEncoding.EncodeToString(attr.Data)
+		case unix.WGDEVICE_A_LISTEN_PORT:
+			if len(attr.Data) >= 2 {
+				device.ListenPort = int(nativeEndian.Uint16(attr.Data))
+			}
+		case unix.WGDEVICE_A_PEERS:
+			for _, peerAttr := range parseAttrs(attr.Data) {
+				parseWireGuardPeer(device, peerAttr.Data)
+			}
+		}
+	}
+}
+
+func parseWireGuardPeer(device *WireGuardDevice, data []byte) {
+	var peer WireGuardPeerState
+	var allowedIPs []string
+
+	for _, attr := range parseAttrs(data) {
+		switch attr.Type {
+		case unix.WGPEER_A_PUBLIC_KEY:
+			peer.PublicKey = base64.StdEncoding.EncodeToString(attr.Data)
+		case unix.WGPEER_A_ENDPOINT:
+			peer.Endpoint = parseSockaddr(attr.Data)
+		case unix.WGPEER_A_PERSISTENT_KEEPALIVE_INTERVAL:
+			if len(attr.Data) >= 2 {
+				peer.PersistentKeepalive = int(nativeEndian.Uint16(attr.Data))
+			}
+		case unix.WGPEER_A_LAST_HANDSHAKE_TIME:
+			if len(attr.Data) >= 16 {
+				sec := int64(nativeEndian.Uint64(attr.Data[0:8]))
+				nsec := int64(nativeEndian.Uint64(attr.Data[8:16]))
+				if sec != 0 || nsec != 0 {
+					peer.LastHandshake = time.Unix(sec, nsec)
+				}
+			}
+		case unix.WGPEER_A_RX_BYTES:
+			if len(attr.Data) >= 8 {
+				peer.RxBytes = nativeEndian.Uint64(attr.Data)
+			}
+		case unix.WGPEER_A_TX_BYTES:
+			if len(attr.Data) >= 8 {
+				peer.TxBytes = nativeEndian.Uint64(attr.Data)
+			}
+		case unix.WGPEER_A_ALLOWEDIPS:
+			for _, ipAttr := range parseAttrs(attr.Data) {
+				if ip := parseAllowedIP(ipAttr.Data); ip != "" {
+					allowedIPs = append(allowedIPs, ip)
+				}
+			}
+		}
+	}
+
+	// A peer continued from the previous message only carries more allowed IPs
+	if existing := device.Peer(peer.PublicKey); existing != nil {
+		existing.AllowedIPs = append(existing.AllowedIPs, allowedIPs...)
+		return
+	}
+
+	peer.AllowedIPs = allowedIPs
+	device.Peers = append(device.Peers, &peer)
+}
+
+func parseAllowedIP(data []byte) string {
+	var ip net.IP
+	cidr := -1
+
+	for _, attr := range parseAttrs(data) {
+		switch attr.Type {
+		case unix.WGALLOWEDIP_A_IPADDR:
+			ip = net.IP(append([]byte(nil), attr.Data...))
+		case unix.WGALLOWEDIP_A_CIDR_MASK:
+			if len(attr.Data) >= 1 {
+				cidr = int(attr.Data[0])
+			}
+		}
+	}
+
+	if ip == nil || cidr < 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s/%d", ip, cidr)
+}
+
+// parseSockaddr formats a sockaddr_in or sockaddr_in6 as HOST:PORT
+func parseSockaddr(data []byte) string {
+	if len(data) < 4 {
+		return ""
+	}
+
+	family := nativeEndian.Uint16(data[0:2])
+	port := strconv.Itoa(int(binary.BigEndian.Uint16(data[2:4])))
+
+	switch {
+	case family == unix.AF_INET && len(data) >= 8:
+		return net.JoinHostPort(net.IP(data[4:8]).String(), port)
+	case family == unix.AF_INET6 && len(data) >= 24:
+		return net.JoinHostPort(net.IP(data[8:24]).String(), port)
+	}
+
+	return ""
+}
@@ -0,0 +1,275 @@
+package networkd
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+const wireGuardPeerSection = "WireGuardPeer"
+
+// WireGuardPeer is a [WireGuardPeer] section of a wireguard netdev
+type WireGuardPeer struct {
+	PublicKey           string
+	AllowedIPs          []string
+	Endpoint            string
+	PersistentKeepalive string
+	PresharedKeyFile    string
+}
+
+func validateWireGuard(settings map[string]string) error {
+	if settings["PrivateKey"] == "" && settings["PrivateKeyFile"] == "" {
+		return fmt.Errorf("A WireGuard PrivateKey or PrivateKeyFile is required")
+	}
+
+	if port, ok := settings["ListenPort"]; ok && port != "auto" {
+		value, err := strconv.Atoi(port)
+		if err != nil || value < 1 || value > 65535 {
+			return fmt.Errorf("The WireGuard ListenPort %s must be between 1 and 65535 or auto", port)
+		}
+	}
+
+	return nil
+}
+
+// validateKey checks for a base64 encoded Curve25519 key
+func validateKey(key string) error {
+	data, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(data) != curve25519.ScalarSize {
+		return fmt.Errorf("The key %s is not a valid WireGuard key", key)
+	}
+	return nil
+}
+
+func generatePrivateKey() (string, error) {
+	key := make([]byte, curve25519.ScalarSize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+
+	// Clamp the scalar as described by RFC 7748
+	key[0] &= 248
+	key[31] &= 127
+	key[31] |= 64
+
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+func derivePublicKey(privateKey string) (string, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(privateKey))
+	if err != nil || len(key) != curve25519.ScalarSize {
+		return "", fmt.Errorf("The private key is not a valid WireGuard key")
+	}
+
+	public, err := curve25519.X25519(key, curve25519.Basepoint)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(public), nil
+}
+
+// CreateWireGuard creates a wireguard netdev with a newly generated private
+// key in the default inventory. The key is written to KeyDir where only
+// root and systemd-networkd can read it.
+func CreateWireGuard(config *NetDevConfig) (*NetDev, error) {
+	return defaultInventory.CreateWireGuard(config)
+}
+
+// CreateWireGuard creates a wireguard netdev with a newly generated private
+// key in the inventory
+func (self *Inventory) CreateWireGuard(config *NetDevConfig) (*NetDev, error) {
+	config.Kind = "wireguard"
+	if config.Settings == nil {
+		config.Settings = make(map[string]string)
+	}
+
+	if err := ValidateLinkName(config.Name); err != nil {
+		return nil, err
+	}
+
+	keyFile := filepath.Join(options.KeyDir, config.Name+".key")
+	if fileExists(options.Path(keyFile)) {
+		return nil, fmt.Errorf("The private key %s already exists", options.Path(keyFile))
+	}
+	config.Settings["PrivateKeyFile"] = keyFile
+
+	netdev, err := self.CreateNetDev(config)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, err := generatePrivateKey()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to generate a private key: %w", err)
+	}
+
+	if err := writeSecret(options.Path(keyFile), []byte(privateKey+"\n")); err != nil {
+		return nil, fmt.Errorf("Failed to save private key %s: %w", options.Path(keyFile), err)
+	}
+
+	return netdev, nil
+}
+
+// PublicKey derives the public key of a wireguard netdev from its private
+// key.
+func (self *NetDev) PublicKey() (string, error) {
+	unit, err := self.wireGuardUnit()
+	if err != nil {
+		return "", err
+	}
+
+	if privateKey := unit.Get("WireGuard", "PrivateKey"); privateKey != "" {
+		return derivePublicKey(privateKey)
+	}
+
+	keyFile := unit.Get("WireGuard", "PrivateKeyFile")
+	if keyFile == "" {
+		return "", fmt.Errorf("The link %s does not have a private key", self.Name)
+	}
+
+	data, err := readFile(options.Path(keyFile))
+	if err != nil {
+		return "", fmt.Errorf("Unable to read private key %s: %w", keyFile, err)
+	}
+
+	return derivePublicKey(string(data))
+}
+
+// WireGuardPeers returns the peers configured in the netdev's unit
+func (self *NetDev) WireGuardPeers() ([]*WireGuardPeer, error) {
+	unit, err := self.wireGuardUnit()
+	if err != nil {
+		return nil, err
+	}
+
+	var peers []*WireGuardPeer
+	for _, section := range unit.File.SectionsByName(wireGuardPeerSection) {
+		peer := WireGuardPeer{
+			PublicKey:           section.Get("PublicKey"),
+			Endpoint:            section.Get("Endpoint"),
+			PersistentKeepalive: section.Get("PersistentKeepalive"),
+			PresharedKeyFile:    section.Get("PresharedKeyFile"),
+		}
+		for _, ip := range strings.Split(strings.Join(section.Values("AllowedIPs"), ","), ",") {
+			if ip = strings.TrimSpace(ip); ip != "" {
+				peer.AllowedIPs = append(peer.AllowedIPs, ip)
+			}
+		}
+		peers = append(peers, &peer)
+	}
+
+	return peers, nil
+}
+
+// AddPeer appends a [WireGuardPeer] section to the netdev's unit
+func (self *NetDev) AddPeer(peer *WireGuardPeer) error {
+	if err := peer.validate(); err != nil {
+		return err
+	}
+
+	unit, err := self.wireGuardUnit()
+	if err != nil {
+		return err
+	}
+
+	if peerSection(unit, peer.PublicKey) != nil {
+		return fmt.Errorf("The peer %s is already configured for link %s", peer.PublicKey, self.Name)
+	}
+
+	section := unit.File.NewSection(wireGuardPeerSection)
+	section.Set("PublicKey", peer.PublicKey)
+	if len(peer.AllowedIPs) > 0 {
+		section.Set("AllowedIPs", strings.Join(peer.AllowedIPs, ","))
+	}
+	if peer.Endpoint != "" {
+		section.Set("Endpoint", peer.Endpoint)
+	}
+	if peer.PersistentKeepalive != "" {
+		section.Set("PersistentKeepalive", peer.PersistentKeepalive)
+	}
+	if peer.PresharedKeyFile != "" {
+		section.Set("PresharedKeyFile", peer.PresharedKeyFile)
+	}
+
+	touchLink(self.Name)
+	if err := unit.Save(); err != nil {
+		return fmt.Errorf("Failed to save unit %s: %w", unit.Path, err)
+	}
+
+	return nil
+}
+
+// RemovePeer removes the [WireGuardPeer] section with the given public key
+func (self *NetDev) RemovePeer(publicKey string) error {
+	unit, err := self.wireGuardUnit()
+	if err != nil {
+		return err
+	}
+
+	section := peerSection(unit, publicKey)
+	if section == nil {
+		return fmt.Errorf("The peer %s is not configured for link %s", publicKey, self.Name)
+	}
+	unit.File.DeleteSection(section)
+
+	touchLink(self.Name)
+	if err := unit.Save(); err != nil {
+		return fmt.Errorf("Failed to save unit %s: %w", unit.Path, err)
+	}
+
+	return nil
+}
+
+// wireGuardUnit loads the file defining a wireguard netdev so its peers
+// can be edited in place.
+func (self *NetDev) wireGuardUnit() (*Unit, error) {
+	if self.Kind != "wireguard" {
+		return nil, fmt.Errorf("The link %s is a %s link, not wireguard", self.Name, self.Kind)
+	}
+
+	return NewUnit(self.SourcePath())
+}
+
+func (self *WireGuardPeer) validate() error {
+	if err := validateKey(self.PublicKey); err != nil {
+		return err
+	}
+
+	for _, ip := range self.AllowedIPs {
+		if _, _, err := net.ParseCIDR(ip); err != nil && net.ParseIP(ip) == nil {
+			return fmt.Errorf("The allowed IP %s is not an address or prefix", ip)
+		}
+	}
+
+	if self.Endpoint != "" {
+		if _, port, err := net.SplitHostPort(self.Endpoint); err != nil || port == "" {
+			return fmt.Errorf("The endpoint %s must be HOST:PORT", self.Endpoint)
+		}
+	}
+
+	if self.PersistentKeepalive != "" && self.PersistentKeepalive != "off" {
+		value, err := strconv.Atoi(self.PersistentKeepalive)
+		if err != nil || value < 0 || value > 65535 {
+			return fmt.Errorf("The keepalive interval %s must be between 0 and 65535 seconds",
+				self.PersistentKeepalive)
+		}
+	}
+
+	return nil
+}
+
+func peerSection(unit *Unit, publicKey string) *UnitSection {
+	for _, section := range unit.File.SectionsByName(wireGuardPeerSection) {
+		if section.Get("PublicKey") == publicKey {
+			return section
+		}
+	}
+	return nil
+}
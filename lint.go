@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/haboustak/linkctl/internal/networkd"
+)
+
+var cmdLint = &Command{
+	Name: "lint",
+	Run:  lint,
+	Usage: `Usage:
+    linkctl [-h] lint [-q] [-o FORMAT]
+
+Validate the settings of every netdev unit and of the network units that
+attach netdevs to interfaces. Masked links are not checked.
+
+Errors are settings systemd-networkd rejects or that keep a link from
+being created: a missing or invalid link name, an unknown Kind, a VLAN Id
+outside 1-4094, a VXLAN link without a VNI, WireGuard keys that are missing
+or malformed, and network units that attach a link that is not defined.
+The problems reported by check are errors as well.
+
+Warnings are settings systemd-networkd ignores, such as lines without an
+assignment and sections and keys the link's Kind does not use, and network
+units that attach a disabled link.
+
+Exit status:
+    0           no findings
+    1           linkctl failed
+    2           only warnings were found
+    3           errors were found
+
+Options:
+    -h          show this help
+    -o FORMAT   output format: table, json or yaml (default table)
+    -q          do not print the count of errors and warnings
+`,
+}
+
+var (
+	lintFormat string
+	lintQuiet  bool
+)
+
+func init() {
+	cmdLint.Flags.StringVar(&lintFormat, "o", FormatTable, "output format")
+	cmdLint.Flags.BoolVar(&lintQuiet, "q", false, "do not print the summary")
+}
+
+func lint(self *Command) error {
+	if err := validateFormat(lintFormat); err != nil {
+		return err
+	}
+
+	findings, err := networkd.Lint()
+	if err != nil {
+		return err
+	}
+
+	errorCount := 0
+	for _, finding := range findings {
+		if finding.Severity == networkd.SeverityError {
+			errorCount++
+		}
+	}
+
+	if lintFormat != FormatTable {
+		summaries := []FindingSummary{}
+		for _, finding := range findings {
+			summaries = append(summaries, FindingSummary{
+				Severity: string(finding.Severity),
+				Path:     networkd.SystemPath(finding.Path),
+				Link:     finding.Link,
+				Section:  finding.Section,
+				Key:      finding.Key,
+				Message:  finding.Message,
+			})
+		}
+		if err := writeFormatted(os.Stdout, lintFormat, summaries); err != nil {
+			return err
+		}
+	} else {
+		for _, finding := range findings {
+			fmt.Printf("%s: %s: %s\n", networkd.SystemPath(finding.Path), finding.Severity, finding.Message)
+		}
+		if len(findings) > 0 && !lintQuiet {
+			fmt.Fprintf(os.Stderr, "\n%s, %s\n",
+				plural(errorCount, "error"), plural(len(findings)-errorCount, "warning"))
+		}
+	}
+
+	switch {
+	case errorCount > 0:
+		return exitStatus(3)
+	case len(findings) > 0:
+		return exitStatus(2)
+	}
+	return nil
+}
+
+func plural(count int, noun string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, noun)
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
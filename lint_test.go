@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/haboustak/linkctl/internal/networkd"
+)
+
+func TestLintExitStatus(t *testing.T) {
+	const unit = "etc/linkctl/system/50-eth0.v300.netdev"
+
+	tests := []struct {
+		name string
+		unit string
+		want exitStatus
+	}{
+		{
+			name: "no findings",
+			unit: "[NetDev]\nName=v300\nKind=vlan\n\n[VLAN]\nId=300\n",
+			want: 0,
+		},
+		{
+			name: "warnings",
+			unit: "[NetDev]\nName=v300\nKind=vlan\n\n[VLAN]\nId=300\nColor=blue\n",
+			want: 2,
+		},
+		{
+			name: "errors",
+			unit: "[NetDev]\nName=v300\nKind=vlan\nColor=blue\n",
+			want: 3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := t.TempDir()
+			files := map[string]string{
+				"etc/systemd/network/10-eth0.network": "[Match]\nName=eth0\n",
+				unit:                                  test.unit,
+			}
+			for path, content := range files {
+				fullPath := filepath.Join(root, path)
+				if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			networkd.Configure(networkd.Options{Root: root})
+			defer networkd.Configure(networkd.Options{})
+			lintFormat, lintQuiet = FormatJSON, true
+			defer func() { lintFormat, lintQuiet = FormatTable, false }()
+
+			var status exitStatus
+			if err := lint(cmdLint); err != nil {
+				var ok bool
+				if status, ok = err.(exitStatus); !ok {
+					t.Fatal(err)
+				}
+			}
+			if status != test.want {
+				t.Errorf("exit status = %d, want %d", status, test.want)
+			}
+		})
+	}
+}
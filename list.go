@@ -10,28 +10,48 @@ import (
 )
 
 var (
-	showAll   bool
-	terseMode bool
+	showAll       bool
+	terseMode     bool
+	listFormat    string
+	listConflicts bool
 )
 
 var cmdList = &Command{
 	Name: "list",
 	Run:  list,
 	Usage: `Usage:
-    linkctl [-h] list [-a] [-t]
+    linkctl [-h] list [-a] [-t] [-o FORMAT] [-conflicts]
 
 Show systemd-networkd netdev links
 
+When more than one unit defines a link with the same name, the unit in the
+directory listed first wins:
+
+    /etc/systemd/network
+    /run/systemd/network
+    /usr/lib/systemd/network
+    /etc/linkctl/user
+    /etc/linkctl/system
+    /etc/systemd/network/netdev.available
+
+so an enabled link overrides the available definitions and a user
+definition overrides a system one.
+
 Options:
-    -a      show all links
-    -h      show this help
-    -t      only print link names
+    -a          show all links
+    -conflicts  show the links defined more than once and the units that
+                are ignored
+    -h          show this help
+    -o FORMAT   output format: table, json or yaml (default table)
+    -t          only print link names
 `,
 }
 
 func init() {
 	cmdList.Flags.BoolVar(&showAll, "a", false, "show all links")
 	cmdList.Flags.BoolVar(&terseMode, "t", false, "only print link names")
+	cmdList.Flags.StringVar(&listFormat, "o", FormatTable, "output format")
+	cmdList.Flags.BoolVar(&listConflicts, "conflicts", false, "show links defined more than once")
 }
 
 func ansiPad(status string) string {
@@ -48,29 +68,72 @@ func ansiColorStatus(status networkd.LinkStatus) string {
 		return fmt.Sprintf("\x1B[0;1;32m%s\x1B[0000000m", status)
 	case networkd.LinkUserDefined:
 		return fmt.Sprintf("\x1B[0;1;38;5;185m%s\x1B[0m", status)
+	case networkd.LinkEnabledRuntime:
+		return fmt.Sprintf("\x1B[0;32m%s\x1B[000000000m", status)
+	case networkd.LinkRuntime:
+		return fmt.Sprintf("\x1B[0;1;36m%s\x1B[0000000m", status)
+	case networkd.LinkMasked:
+		return fmt.Sprintf("\x1B[0;1;31m%s\x1B[0000000m", status)
 	default:
 		return ansiPad(string(status))
 	}
 }
 
 func list(self *Command) error {
+	if err := validateFormat(listFormat); err != nil {
+		return err
+	}
+
+	if listConflicts {
+		return listShadowed()
+	}
+
 	links := networkd.ListNetDev(showAll)
-	if links == nil {
-		return nil
+
+	if listFormat != FormatTable {
+		summaries := []LinkSummary{}
+		for _, link := range links {
+			summaries = append(summaries, newLinkSummary(link))
+		}
+		return writeFormatted(os.Stdout, listFormat, summaries)
+	}
+
+	if links != nil {
+		w := tabwriter.NewWriter(os.Stdout, 16, 3, 4, ' ', tabwriter.TabIndent)
+		if !terseMode {
+			fmt.Fprintf(w, "NAME\tTYPE\t%s\tDESCRIPTION\t\n", ansiPad("STATUS"))
+		}
+
+		for _, link := range links {
+			printLink(w, link)
+		}
+
+		w.Flush()
 	}
 
-	w := tabwriter.NewWriter(os.Stdout, 16, 3, 4, ' ', tabwriter.TabIndent)
 	if !terseMode {
-		fmt.Fprintf(w, "NAME\tTYPE\t%s\tDESCRIPTION\t\n", ansiPad("STATUS"))
+		printProblems(os.Stderr, networkd.Problems())
 	}
+	return nil
+}
 
-	for _, link := range links {
-		printLink(w, link)
+// printProblems lists the problems found while discovering links after
+// the table of links
+func printProblems(w io.Writer, problems []networkd.Problem) {
+	if len(problems) == 0 {
+		return
 	}
 
-	w.Flush()
-	return nil
+	noun := "problems"
+	if len(problems) == 1 {
+		noun = "problem"
+	}
+	fmt.Fprintf(w, "\n%d %s found while loading links, see \"linkctl check\":\n", len(problems), noun)
+	for _, problem := range problems {
+		fmt.Fprintf(w, "  %s\n", problem)
+	}
 }
+
 func printLink(w io.Writer, link *networkd.NetDev) {
 	if terseMode {
 		fmt.Fprintln(w, link.Name)
@@ -81,3 +144,59 @@ func printLink(w io.Writer, link *networkd.NetDev) {
 			link.Description)
 	}
 }
+
+// listShadowed lists the links defined by more than one unit, along with
+// the units that are ignored
+func listShadowed() error {
+	var conflicts []*networkd.NetDev
+	for _, netdev := range networkd.ListNetDev(true) {
+		if len(netdev.Shadowed) > 0 {
+			conflicts = append(conflicts, netdev)
+		}
+	}
+
+	if listFormat != FormatTable {
+		summaries := []ConflictSummary{}
+		for _, netdev := range conflicts {
+			summaries = append(summaries, ConflictSummary{
+				Name:     netdev.Name,
+				Status:   string(netdev.Status),
+				Unit:     networkd.SystemPath(netdev.Unit.Path),
+				Shadowed: systemPaths(netdev.Shadowed),
+			})
+		}
+		return writeFormatted(os.Stdout, listFormat, summaries)
+	}
+
+	if terseMode {
+		for _, netdev := range conflicts {
+			fmt.Println(netdev.Name)
+		}
+		return nil
+	}
+
+	if len(conflicts) == 0 {
+		fmt.Println("No link is defined more than once")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 16, 3, 4, ' ', tabwriter.TabIndent)
+	fmt.Fprintf(w, "NAME\tUNIT\tSHADOWED\t\n")
+	for _, netdev := range conflicts {
+		for i, path := range netdev.Shadowed {
+			if i == 0 {
+				fmt.Fprintf(w, "%s\t%s\t%s\t\n", netdev.Name,
+					networkd.SystemPath(netdev.Unit.Path), networkd.SystemPath(path))
+			} else {
+				fmt.Fprintf(w, "\t\t%s\t\n", networkd.SystemPath(path))
+			}
+		}
+	}
+	w.Flush()
+
+	fmt.Println("\nA unit overrides those defining the same link in the directories after it:")
+	for _, dir := range networkd.NetDevDirs() {
+		fmt.Printf("  %s\n", dir)
+	}
+	return nil
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"golang.org/x/sys/unix"
 	"os"
+
+	"github.com/haboustak/linkctl/internal/networkd"
 )
 
 var IsATTY bool
@@ -17,11 +20,30 @@ type Command struct {
 	Usage string
 }
 
+// exitStatus ends linkctl with a status but no message, for commands that
+// report their own results
+type exitStatus int
+
+func (self exitStatus) Error() string {
+	return fmt.Sprintf("exit status %d", int(self))
+}
+
 var commands = []*Command{
 	cmdList,
 	cmdEnable,
 	cmdDisable,
 	cmdRename,
+	cmdCreate,
+	cmdDelete,
+	cmdShow,
+	cmdCat,
+	cmdCheck,
+	cmdLint,
+	cmdApply,
+	cmdExport,
+	cmdBridge,
+	cmdBond,
+	cmdWireGuard,
 }
 
 func init() {
@@ -33,11 +55,16 @@ func init() {
 func main() {
 	var showHelp bool
 	var version bool
+	var root string
+	var dryRun bool
 
 	flag.BoolVar(&showHelp, "h", false, "show help")
 	flag.BoolVar(&showAll, "a", false, "show all links")
 	flag.BoolVar(&terseMode, "t", false, "only print link names")
 	flag.BoolVar(&version, "version", false, "print version information")
+	flag.StringVar(&root, "root", "", "operate on the configuration beneath DIR")
+	flag.BoolVar(&dryRun, "n", false, "print changes instead of applying them")
+	flag.BoolVar(&dryRun, "dry-run", false, "print changes instead of applying them")
 
 	flag.Usage = func() {
 		printUsage(defaultUsage)
@@ -51,6 +78,13 @@ func main() {
 		printVersion()
 	}
 
+	networkd.Configure(networkd.Options{Root: root})
+
+	var plan *networkd.Plan
+	if dryRun {
+		plan = networkd.DryRun()
+	}
+
 	command := "list"
 	if len(args) > 0 {
 		command = args[0]
@@ -71,9 +105,21 @@ func main() {
 			printUsage(cmd.Usage)
 		}
 		cmd.Flags.Parse(args)
-		err := cmd.Run(cmd)
-		if err != nil {
-			fmt.Println(err)
+		status := 0
+		if err := cmd.Run(cmd); err != nil {
+			var exit exitStatus
+			if errors.As(err, &exit) {
+				status = int(exit)
+			} else {
+				fmt.Println(err)
+				status = 1
+			}
+		}
+		if plan != nil {
+			plan.WriteDiff(os.Stdout)
+		}
+		if status != 0 {
+			os.Exit(status)
 		}
 		cmdFound = true
 		break
@@ -88,19 +134,36 @@ func main() {
 var defaultUsage = `linkctl is a tool for managing systemd-networkd virtual interfaces
 
 Usage:
-    linkctl [-h] [-a] [-t] [-version] COMMAND [arguments]
+    linkctl [-h] [-a] [-n] [-t] [-root DIR] [-version] COMMAND [arguments]
 
 Options:
    -a           show all links
    -h           show this help
+   -n, --dry-run
+                print the changes a command would make as a diff
+                instead of applying them
+   -root DIR    operate on the configuration beneath DIR instead of the
+                running system. Interfaces are matched to network units
+                by name and the running system's links are ignored
    -t           only print link names
    -version     print version information
 
 Commands:
     list        list netdev links
+    show        show the configuration and state of a netdev link
+    cat         print the effective configuration of a netdev link
+    check       report units that prevent links from being discovered
+    lint        validate the settings of netdev and network units
     enable      enable a netdev link
     disable     disable a netdev link
     rename      rename a netdev link
+    create      create a netdev link definition
+    delete      delete a netdev link definition
+    apply       change links to match a manifest
+    export      print a manifest of every netdev link
+    bridge      manage the ports of bridges
+    bond        manage the members of bonds
+    wg          manage WireGuard links and their peers
 `
 
 func printUsage(usage string) {
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/haboustak/linkctl/internal/networkd"
+)
+
+// Manifest is the desired state of the links on a host. It is read by
+// apply and written by export, as YAML or JSON.
+type Manifest struct {
+	Links []*ManifestLink `json:"links" yaml:"links"`
+}
+
+func newManifest(links []*networkd.NetDev) *Manifest {
+	manifest := &Manifest{Links: []*ManifestLink{}}
+	for _, netdev := range links {
+		description := netdev.Description
+		manifest.Links = append(manifest.Links, &ManifestLink{
+			Unit:        netdev.Unit.Name,
+			Name:        netdev.Name,
+			Status:      string(netdev.Status),
+			Description: &description,
+		})
+	}
+	return manifest
+}
+
+// ManifestLink is the desired state of a single link. The link is found by
+// its unit file, or by its name when no unit is given. Settings that are
+// left out are not changed.
+type ManifestLink struct {
+	Unit        string  `json:"unit,omitempty" yaml:"unit,omitempty"`
+	Name        string  `json:"name,omitempty" yaml:"name,omitempty"`
+	Status      string  `json:"status,omitempty" yaml:"status,omitempty"`
+	Description *string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// readManifest reads a manifest from path, or from stdin if path is "-".
+// JSON is read as YAML, of which it is a subset.
+func readManifest(path string) (*Manifest, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read manifest: %w", err)
+	}
+
+	var manifest Manifest
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&manifest); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("Failed to parse manifest %s: %w", path, err)
+	}
+
+	if err := manifest.validate(); err != nil {
+		return nil, fmt.Errorf("Invalid manifest %s: %w", path, err)
+	}
+
+	return &manifest, nil
+}
+
+func (self *Manifest) validate() error {
+	seen := make(map[string]bool)
+	for i, link := range self.Links {
+		if link == nil || (link.Unit == "" && link.Name == "") {
+			return fmt.Errorf("link %d must have a unit or a name", i+1)
+		}
+
+		id := link.id()
+		if seen[id] {
+			return fmt.Errorf("%s is listed more than once", id)
+		}
+		seen[id] = true
+
+		// Exported manifests also record the status of links linkctl cannot
+		// change, applying them only checks the status still matches
+		switch networkd.LinkStatus(link.Status) {
+		case "", networkd.LinkEnabled, networkd.LinkEnabledRuntime, networkd.LinkDisabled,
+			networkd.LinkUserDefined, networkd.LinkRuntime, networkd.LinkVendor, networkd.LinkMasked:
+		default:
+			return fmt.Errorf("%s has an unknown status %s", id, link.Status)
+		}
+	}
+	return nil
+}
+
+// id is how the link is identified in messages
+func (self *ManifestLink) id() string {
+	if self.Unit != "" {
+		return self.Unit
+	}
+	return self.Name
+}
+
+// find returns the netdev the manifest entry describes
+func (self *ManifestLink) find() (*networkd.NetDev, error) {
+	if self.Unit == "" {
+		netdev, ok := networkd.GetNetDev(self.Name)
+		if !ok {
+			return nil, fmt.Errorf("No link with the name %s", self.Name)
+		}
+		return netdev, nil
+	}
+
+	netdev, ok := networkd.GetNetDevByUnit(self.Unit)
+	if !ok {
+		return nil, fmt.Errorf("No link defined by the unit %s", self.Unit)
+	}
+	return netdev, nil
+}
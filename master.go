@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/haboustak/linkctl/internal/networkd"
+)
+
+var cmdBridge = &Command{
+	Name: "bridge",
+	Run:  bridge,
+	Usage: `Usage:
+    linkctl [-h] bridge [list]
+    linkctl [-h] bridge add-port BRIDGE IFACE
+    linkctl [-h] bridge remove-port BRIDGE IFACE
+
+Manage the ports of bridges
+
+Commands:
+    list            list bridges and their ports
+    add-port        add IFACE to BRIDGE
+    remove-port     remove IFACE from BRIDGE
+
+Options:
+    -h      show this help
+`,
+}
+
+var cmdBond = &Command{
+	Name: "bond",
+	Run:  bond,
+	Usage: `Usage:
+    linkctl [-h] bond [list]
+    linkctl [-h] bond add-member BOND IFACE
+    linkctl [-h] bond remove-member BOND IFACE
+
+Manage the members of bonds
+
+Commands:
+    list            list bonds and their members
+    add-member      add IFACE to BOND
+    remove-member   remove IFACE from BOND
+
+Options:
+    -h      show this help
+`,
+}
+
+func bridge(self *Command) error {
+	return runMaster(self, "bridge", "add-port", "remove-port")
+}
+
+func bond(self *Command) error {
+	return runMaster(self, "bond", "add-member", "remove-member")
+}
+
+func runMaster(self *Command, kind string, add string, remove string) error {
+	args := self.Flags.Args()
+
+	operation := "list"
+	if len(args) > 0 {
+		operation = args[0]
+		args = args[1:]
+	}
+
+	switch operation {
+	case "list":
+		return listMembers(kind)
+	case add, remove:
+	default:
+		return fmt.Errorf("Unknown %s operation \"%s\", try \"linkctl %s -h\"", kind, operation, kind)
+	}
+
+	if len(args) != 2 {
+		return errors.New("You must provide the name of the master and the interface")
+	}
+	master, member := args[0], args[1]
+
+	return networkd.Apply(func() error {
+		if operation == add {
+			return networkd.AddMember(kind, master, member)
+		}
+		return networkd.RemoveMember(kind, master, member)
+	})
+}
+
+func listMembers(kind string) error {
+	masters, err := networkd.ListMembers(kind)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for name := range masters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(os.Stdout, 16, 3, 4, ' ', tabwriter.TabIndent)
+	fmt.Fprintf(w, "%s\tMEMBERS\t\n", strings.ToUpper(kind))
+	for _, name := range names {
+		var members []string
+		for _, member := range masters[name] {
+			switch {
+			case member.Configured && member.Active:
+				members = append(members, member.Name)
+			case member.Configured:
+				members = append(members, member.Name+" (configured)")
+			default:
+				members = append(members, member.Name+" (unconfigured)")
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t\n", name, strings.Join(members, ", "))
+	}
+
+	return w.Flush()
+}
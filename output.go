@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/haboustak/linkctl/internal/networkd"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+	FormatYAML  = "yaml"
+)
+
+// LinkSummary is the stable representation of a link used by every
+// machine-readable output format.
+type LinkSummary struct {
+	Name        string `json:"name" yaml:"name"`
+	Kind        string `json:"kind" yaml:"kind"`
+	Status      string `json:"status" yaml:"status"`
+	Description string `json:"description" yaml:"description"`
+	Unit        string `json:"unit" yaml:"unit"`
+	Parent      string `json:"parent" yaml:"parent"`
+	OperState   string `json:"operational_state" yaml:"operational_state"`
+}
+
+// LinkDetail extends LinkSummary with everything show prints
+type LinkDetail struct {
+	LinkSummary   `yaml:",inline"`
+	Source        string          `json:"source" yaml:"source"`
+	Dropins       []string        `json:"dropins" yaml:"dropins"`
+	RenameUnits   []string        `json:"rename_units" yaml:"rename_units"`
+	ParentNetwork []string        `json:"parent_network" yaml:"parent_network"`
+	Network       []string        `json:"network" yaml:"network"`
+	Shadowed      []string        `json:"shadowed" yaml:"shadowed"`
+	Interface     *InterfaceState `json:"interface" yaml:"interface"`
+}
+
+// ConflictSummary is a link defined by more than one unit
+type ConflictSummary struct {
+	Name     string   `json:"name" yaml:"name"`
+	Status   string   `json:"status" yaml:"status"`
+	Unit     string   `json:"unit" yaml:"unit"`
+	Shadowed []string `json:"shadowed" yaml:"shadowed"`
+}
+
+// ProblemSummary is a problem found while discovering links
+type ProblemSummary struct {
+	Path    string `json:"path" yaml:"path"`
+	Link    string `json:"link" yaml:"link"`
+	Message string `json:"message" yaml:"message"`
+}
+
+// FindingSummary is an issue found by lint
+type FindingSummary struct {
+	Severity string `json:"severity" yaml:"severity"`
+	Path     string `json:"path" yaml:"path"`
+	Link     string `json:"link" yaml:"link"`
+	Section  string `json:"section" yaml:"section"`
+	Key      string `json:"key" yaml:"key"`
+	Message  string `json:"message" yaml:"message"`
+}
+
+type InterfaceState struct {
+	Index     int      `json:"index" yaml:"index"`
+	Kind      string   `json:"kind" yaml:"kind"`
+	OperState string   `json:"operstate" yaml:"operstate"`
+	Parent    string   `json:"parent" yaml:"parent"`
+	Master    string   `json:"master" yaml:"master"`
+	VlanId    int      `json:"vlan_id" yaml:"vlan_id"`
+	MTU       int      `json:"mtu" yaml:"mtu"`
+	MAC       string   `json:"mac" yaml:"mac"`
+	Flags     []string `json:"flags" yaml:"flags"`
+	Addresses []string `json:"addresses" yaml:"addresses"`
+}
+
+func validateFormat(format string) error {
+	switch format {
+	case FormatTable, FormatJSON, FormatYAML:
+		return nil
+	}
+	return fmt.Errorf("Unknown output format \"%s\", expected table, json or yaml", format)
+}
+
+func writeFormatted(w io.Writer, format string, value interface{}) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	case FormatYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		defer encoder.Close()
+		return encoder.Encode(value)
+	}
+	return validateFormat(format)
+}
+
+func newLinkSummary(netdev *networkd.NetDev) LinkSummary {
+	summary := LinkSummary{
+		Name:        netdev.Name,
+		Kind:        netdev.Kind,
+		Status:      string(netdev.Status),
+		Description: netdev.Description,
+		Unit:        networkd.SystemPath(netdev.Unit.Path),
+		OperState:   netdev.Interface.OperState(),
+	}
+	if netdev.ParentNetwork != nil {
+		summary.Parent = netdev.ParentNetwork.Interface.Name
+	}
+	return summary
+}
+
+func systemPaths(paths []string) []string {
+	systemPaths := []string{}
+	for _, path := range paths {
+		systemPaths = append(systemPaths, networkd.SystemPath(path))
+	}
+	return systemPaths
+}
+
+func newLinkDetail(netdev *networkd.NetDev) LinkDetail {
+	detail := LinkDetail{
+		LinkSummary:   newLinkSummary(netdev),
+		Source:        networkd.SystemPath(netdev.SourcePath()),
+		Dropins:       unitPaths(netdev.Dropins...),
+		RenameUnits:   unitPaths(netdev.RenameUnit, netdev.RenameNetworkUnit),
+		ParentNetwork: networkPaths(netdev.ParentNetwork),
+		Network:       networkPaths(netdev.Network),
+		Shadowed:      systemPaths(netdev.Shadowed),
+	}
+
+	netif := netdev.Interface.NetIf
+	if netif == nil {
+		return detail
+	}
+
+	detail.Interface = &InterfaceState{
+		Index:     netif.Index,
+		MTU:       netif.MTU,
+		MAC:       netif.HardwareAddr.String(),
+		Flags:     []string{},
+		Addresses: []string{},
+	}
+	if link := netdev.Interface.Link; link != nil {
+		detail.Interface.Kind = link.Kind
+		detail.Interface.OperState = link.OperState
+		detail.Interface.Parent = link.Parent
+		detail.Interface.Master = link.Master
+		detail.Interface.VlanId = link.VlanId
+	}
+	if netif.Flags != 0 {
+		detail.Interface.Flags = strings.Split(netif.Flags.String(), "|")
+	}
+	if addrs, err := netif.Addrs(); err == nil {
+		for _, addr := range addrs {
+			detail.Interface.Addresses = append(detail.Interface.Addresses, addr.String())
+		}
+	}
+
+	return detail
+}
@@ -0,0 +1,161 @@
+// Package linkctl manages systemd-networkd netdev links: listing them,
+// enabling and disabling them, renaming them and creating new definitions.
+//
+//	manager := linkctl.NewManager(linkctl.Options{})
+//	links, err := manager.List(ctx, true)
+//	...
+//	err = manager.Enable(ctx, "test.300")
+//
+// Changes are written to the configuration directories and
+// systemd-networkd is asked to reconfigure the affected links. A change
+// that fails is rolled back before the error is returned.
+package linkctl
+
+import (
+	"errors"
+
+	"github.com/haboustak/linkctl/internal/networkd"
+)
+
+// Options describes where links are found and written. Empty directories
+// fall back to the systemd-networkd and linkctl defaults, and an empty Root
+// refers to the running system.
+type Options struct {
+	// Root is prepended to every other directory, e.g. to manage the links
+	// of an image
+	Root string
+	// NetworkDir is where systemd-networkd reads enabled units
+	NetworkDir string
+	// RuntimeDir and VendorDir are also read by systemd-networkd, for
+	// units generated at runtime or shipped by packages
+	RuntimeDir string
+	VendorDir  string
+	// UserDir, SystemDir and AvailableDir hold the links available to be
+	// enabled, in order of precedence
+	UserDir      string
+	SystemDir    string
+	AvailableDir string
+	// StateDir holds the link state files written by systemd-networkd
+	StateDir string
+	// KeyDir holds the private keys of WireGuard links
+	KeyDir string
+}
+
+// networkdOptions returns the internal options for opts. The progress
+// message the linkctl command prints while systemd-networkd reloads is
+// always suppressed.
+func (self Options) networkdOptions() networkd.Options {
+	return networkd.Options{
+		Root:         self.Root,
+		NetworkDir:   self.NetworkDir,
+		RuntimeDir:   self.RuntimeDir,
+		VendorDir:    self.VendorDir,
+		UserDir:      self.UserDir,
+		SystemDir:    self.SystemDir,
+		AvailableDir: self.AvailableDir,
+		StateDir:     self.StateDir,
+		KeyDir:       self.KeyDir,
+		Quiet:        true,
+	}
+}
+
+// Status describes how a link is enabled
+type Status string
+
+const (
+	StatusEnabled        Status = networkd.LinkEnabled
+	StatusEnabledRuntime Status = networkd.LinkEnabledRuntime
+	StatusDisabled       Status = networkd.LinkDisabled
+	StatusUserDefined    Status = networkd.LinkUserDefined
+	StatusRuntime        Status = networkd.LinkRuntime
+	StatusVendor         Status = networkd.LinkVendor
+	StatusMasked         Status = networkd.LinkMasked
+)
+
+// ErrNotFound is returned, wrapped, when no link has the requested name
+var ErrNotFound = errors.New("No such link")
+
+// Link is a snapshot of a netdev link. It is not updated by later changes,
+// get the link again to see them.
+type Link struct {
+	Name        string
+	Kind        string
+	Status      Status
+	Description string
+	// Unit is the file systemd-networkd reads, Source is the file that
+	// defines the link, which differs for enabled links
+	Unit    string
+	Source  string
+	Dropins []string
+	// Shadowed are the units that define a link with the same name but
+	// are ignored in favor of Unit
+	Shadowed []string
+	// Parent is the interface a VLAN, MACVLAN or similar link is created
+	// on, it is empty for standalone links such as bridges
+	Parent string
+}
+
+// LinkConfig describes a new link definition
+type LinkConfig struct {
+	Kind        string
+	Name        string
+	Parent      string
+	Description string
+	// Prefix orders the unit among the other networkd units, 50 by default
+	Prefix string
+	// Settings are written to the kind-specific section, e.g. Id to [VLAN]
+	Settings map[string]string
+}
+
+// Problem is a file that prevented a link from being discovered as it is
+// configured, such as a unit that cannot be parsed
+type Problem struct {
+	// Path is the file responsible
+	Path    string
+	Link    string
+	Message string
+}
+
+// Severity describes how serious a Finding is
+type Severity string
+
+const (
+	// SeverityError findings keep a link from being created as configured
+	SeverityError Severity = Severity(networkd.SeverityError)
+	// SeverityWarning findings are settings systemd-networkd ignores
+	SeverityWarning Severity = Severity(networkd.SeverityWarning)
+)
+
+// Finding is an issue found by Lint in a unit or drop-in
+type Finding struct {
+	Severity Severity
+	Path     string
+	Link     string
+	// Section and Key locate the setting responsible, when there is one
+	Section string
+	Key     string
+	Message string
+}
+
+func newLink(netdev *networkd.NetDev) Link {
+	link := Link{
+		Name:        netdev.Name,
+		Kind:        netdev.Kind,
+		Status:      Status(netdev.Status),
+		Description: netdev.Description,
+		Unit:        networkd.SystemPath(netdev.Unit.Path),
+		Source:      networkd.SystemPath(netdev.SourcePath()),
+		Dropins:     []string{},
+		Shadowed:    []string{},
+	}
+	for _, path := range netdev.Shadowed {
+		link.Shadowed = append(link.Shadowed, networkd.SystemPath(path))
+	}
+	for _, dropin := range netdev.Dropins {
+		link.Dropins = append(link.Dropins, networkd.SystemPath(dropin.Path))
+	}
+	if netdev.ParentNetwork != nil {
+		link.Parent = netdev.ParentNetwork.Interface.Name
+	}
+	return link
+}
@@ -0,0 +1,281 @@
+package linkctl
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/haboustak/linkctl/internal/networkd"
+)
+
+// Manager discovers links and changes them. Links are discovered on first
+// use and kept up to date as the Manager changes them, Refresh picks up
+// changes made by others. A Manager is safe for concurrent use, and several
+// Managers with different options may be used at once: their calls are
+// serialized and each applies its own options.
+type Manager struct {
+	options   networkd.Options
+	inventory *networkd.Inventory
+}
+
+// mutex serializes calls to every Manager, as the options of the
+// networkd package are shared by the whole process
+var mutex sync.Mutex
+
+// active is the Manager whose options are configured
+var active *Manager
+
+// NewManager returns a Manager for the links described by opts
+func NewManager(opts Options) *Manager {
+	return &Manager{
+		options:   opts.networkdOptions(),
+		inventory: networkd.NewInventory(),
+	}
+}
+
+// lock serializes a call and configures the Manager's options
+func (self *Manager) lock() {
+	mutex.Lock()
+	if active != self {
+		networkd.Configure(self.options)
+		active = self
+	}
+}
+
+func (self *Manager) unlock() {
+	mutex.Unlock()
+}
+
+// Refresh discards every cached link and discovers them again
+func (self *Manager) Refresh(ctx context.Context) error {
+	self.lock()
+	defer self.unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return self.inventory.Refresh()
+}
+
+func (self *Manager) load(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return self.inventory.Load()
+}
+
+// List returns the enabled links sorted by name, or every link if all is
+// set.
+func (self *Manager) List(ctx context.Context, all bool) ([]Link, error) {
+	self.lock()
+	defer self.unlock()
+
+	if err := self.load(ctx); err != nil {
+		return nil, err
+	}
+
+	links := []Link{}
+	for _, netdev := range self.inventory.List(all) {
+		links = append(links, newLink(netdev))
+	}
+	return links, nil
+}
+
+// Problems returns the files that prevented links from being discovered as
+// they are configured
+func (self *Manager) Problems(ctx context.Context) ([]Problem, error) {
+	self.lock()
+	defer self.unlock()
+
+	if err := self.load(ctx); err != nil {
+		return nil, err
+	}
+
+	problems := []Problem{}
+	for _, problem := range self.inventory.Problems() {
+		problems = append(problems, Problem{
+			Path:    networkd.SystemPath(problem.Path),
+			Link:    problem.Link,
+			Message: problem.Message,
+		})
+	}
+	return problems, nil
+}
+
+// Lint validates the settings of every netdev unit and of the network
+// units that attach netdevs to interfaces. Problems are reported as
+// findings with the SeverityError severity.
+func (self *Manager) Lint(ctx context.Context) ([]Finding, error) {
+	self.lock()
+	defer self.unlock()
+
+	if err := self.load(ctx); err != nil {
+		return nil, err
+	}
+
+	results, err := self.inventory.Lint()
+	if err != nil {
+		return nil, err
+	}
+
+	findings := []Finding{}
+	for _, finding := range results {
+		findings = append(findings, Finding{
+			Severity: Severity(finding.Severity),
+			Path:     networkd.SystemPath(finding.Path),
+			Link:     finding.Link,
+			Section:  finding.Section,
+			Key:      finding.Key,
+			Message:  finding.Message,
+		})
+	}
+	return findings, nil
+}
+
+// Get returns the link with the given name
+func (self *Manager) Get(ctx context.Context, name string) (Link, error) {
+	self.lock()
+	defer self.unlock()
+
+	netdev, err := self.get(ctx, name)
+	if err != nil {
+		return Link{}, err
+	}
+	return newLink(netdev), nil
+}
+
+func (self *Manager) get(ctx context.Context, name string) (*networkd.NetDev, error) {
+	if err := self.load(ctx); err != nil {
+		return nil, err
+	}
+
+	netdev, ok := self.inventory.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return netdev, nil
+}
+
+// change applies fn to the named link and reloads systemd-networkd
+func (self *Manager) change(ctx context.Context, name string, fn func(netdev *networkd.NetDev) error) error {
+	self.lock()
+	defer self.unlock()
+
+	netdev, err := self.get(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	return networkd.ApplyContext(ctx, func() error {
+		return fn(netdev)
+	})
+}
+
+// Enable enables a link available in the linkctl directories
+func (self *Manager) Enable(ctx context.Context, name string) error {
+	return self.change(ctx, name, (*networkd.NetDev).Enable)
+}
+
+// EnableRuntime enables a link until the next reboot
+func (self *Manager) EnableRuntime(ctx context.Context, name string) error {
+	return self.change(ctx, name, (*networkd.NetDev).EnableRuntime)
+}
+
+// Disable disables a link, whether it was enabled persistently or until
+// the next reboot.
+func (self *Manager) Disable(ctx context.Context, name string) error {
+	return self.change(ctx, name, func(netdev *networkd.NetDev) error {
+		if netdev.Status == networkd.LinkEnabledRuntime {
+			return netdev.DisableRuntime()
+		}
+		return netdev.Disable()
+	})
+}
+
+// Rename renames a link with a drop-in. An empty newName resets the link
+// to the name in its unit.
+func (self *Manager) Rename(ctx context.Context, name string, newName string) error {
+	return self.change(ctx, name, func(netdev *networkd.NetDev) error {
+		if newName == "" {
+			return netdev.ResetName()
+		}
+
+		if err := networkd.ValidateLinkName(newName); err != nil {
+			return err
+		}
+		if _, ok := self.inventory.Get(newName); ok {
+			return fmt.Errorf("A link with the name %s already exists", newName)
+		}
+		if _, err := net.InterfaceByName(newName); err == nil {
+			return fmt.Errorf("A link with the name %s already exists", newName)
+		}
+
+		return netdev.Rename(newName)
+	})
+}
+
+// Delete removes a link definition along with the drop-ins linkctl wrote
+// for it, disabling it first if needed. User-defined links are only
+// deleted when forced.
+func (self *Manager) Delete(ctx context.Context, name string, force bool) error {
+	self.lock()
+	defer self.unlock()
+
+	netdev, err := self.get(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	// Only links networkd knows about need a reload
+	if netdev.Status == networkd.LinkDisabled {
+		tx := networkd.Begin()
+		if err := netdev.Delete(force); err != nil {
+			return tx.Rollback(err)
+		}
+		tx.Commit()
+		return nil
+	}
+
+	return networkd.ApplyContext(ctx, func() error {
+		return netdev.Delete(force)
+	})
+}
+
+// Create writes a new link definition, which is available to be enabled
+// but is not enabled.
+func (self *Manager) Create(ctx context.Context, config LinkConfig) (Link, error) {
+	self.lock()
+	defer self.unlock()
+
+	if err := self.load(ctx); err != nil {
+		return Link{}, err
+	}
+
+	netdevConfig := networkd.NetDevConfig{
+		Kind:        config.Kind,
+		Name:        config.Name,
+		Parent:      config.Parent,
+		Description: config.Description,
+		Prefix:      config.Prefix,
+		Settings:    make(map[string]string),
+	}
+	for key, value := range config.Settings {
+		netdevConfig.Settings[key] = value
+	}
+	create := self.inventory.CreateNetDev
+	if config.Kind == "wireguard" {
+		create = self.inventory.CreateWireGuard
+	}
+
+	// A WireGuard key is written after the unit, roll back the unit if
+	// that fails
+	tx := networkd.Begin()
+	netdev, err := create(&netdevConfig)
+	if err != nil {
+		return Link{}, tx.Rollback(err)
+	}
+	tx.Commit()
+
+	return newLink(netdev), nil
+}
@@ -12,9 +12,9 @@ var cmdRename = &Command{
 	Name: "rename",
 	Run:  rename,
 	Usage: `Usage:
-    linkctl [-h] rename LINK [NEWNAME]
+    linkctl [-h] rename [-wait[=TIMEOUT]] LINK [NEWNAME]
 
-Enable a netdev link
+Rename a netdev link
 
 Arguments:
     LINK        name of the link to rename
@@ -23,14 +23,27 @@ Arguments:
 
 Options:
     -h          show this help
+    -wait[=TIMEOUT]
+                wait until systemd-networkd has configured the renamed
+                link, failing after TIMEOUT (default 30s)
 `,
 }
 
+var renameWait waitFlag
+
+func init() {
+	cmdRename.Flags.Var(&renameWait, "wait", "wait until the link is configured")
+}
+
 func clearName(netdev *networkd.NetDev) error {
 	return netdev.ResetName()
 }
 
 func setName(netdev *networkd.NetDev, name string) error {
+	if err := networkd.ValidateLinkName(name); err != nil {
+		return err
+	}
+
 	if _, ok := networkd.GetNetDev(name); ok {
 		return fmt.Errorf("A link with the name %s already exists", name)
 	}
@@ -55,17 +68,18 @@ func rename(self *Command) error {
 		return fmt.Errorf("No link with the name %s", oldName)
 	}
 
-	if len(args) < 2 {
-		if err := clearName(netdev); err != nil {
-			return err
+	err := networkd.Apply(func() error {
+		if len(args) < 2 {
+			return clearName(netdev)
 		}
-	} else if err := setName(netdev, args[1]); err != nil {
+		return setName(netdev, args[1])
+	})
+	if err != nil {
 		return err
 	}
 
-	if err := networkd.Restart(); err != nil {
-		return fmt.Errorf("Failed to restart systemd-networkd: %s", err)
+	if netdev.Status == networkd.LinkDisabled {
+		return nil
 	}
-
-	return nil
+	return waitForLinks(&renameWait, netdev.Name)
 }
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/haboustak/linkctl/internal/networkd"
+)
+
+var showFormat string
+
+var cmdShow = &Command{
+	Name: "show",
+	Run:  show,
+	Usage: `Usage:
+    linkctl [-h] show [-o FORMAT] LINK
+
+Show the configuration and state of a netdev link
+
+Arguments:
+    LINK        name of the link to show
+
+Options:
+    -h          show this help
+    -o FORMAT   output format: table, json or yaml (default table)
+`,
+}
+
+func init() {
+	cmdShow.Flags.StringVar(&showFormat, "o", FormatTable, "output format")
+}
+
+func printField(w io.Writer, label string, values ...string) {
+	if len(values) == 0 {
+		return
+	}
+
+	for i, value := range values {
+		if i > 0 {
+			label = ""
+		} else {
+			label += ":"
+		}
+		fmt.Fprintf(w, "%16s %s\n", label, value)
+	}
+}
+
+func unitPaths(units ...*networkd.Unit) []string {
+	paths := []string{}
+	for _, unit := range units {
+		if unit != nil {
+			paths = append(paths, networkd.SystemPath(unit.Path))
+		}
+	}
+	return paths
+}
+
+func networkPaths(network *networkd.Network) []string {
+	if network == nil || network.Unit == nil {
+		return []string{}
+	}
+
+	paths := unitPaths(network.Unit)
+	dropins, _ := network.Unit.Dropins()
+	for _, dropin := range dropins {
+		paths = append(paths, networkd.SystemPath(dropin))
+	}
+	return paths
+}
+
+func show(self *Command) error {
+	args := self.Flags.Args()
+
+	if err := validateFormat(showFormat); err != nil {
+		return err
+	}
+
+	if len(args) != 1 {
+		return fmt.Errorf("You must provide the name of the link to show")
+	}
+
+	netdev, ok := networkd.GetNetDev(args[0])
+	if !ok {
+		return fmt.Errorf("No link with the name %s", args[0])
+	}
+
+	if showFormat != FormatTable {
+		return writeFormatted(os.Stdout, showFormat, newLinkDetail(netdev))
+	}
+
+	printNetDev(os.Stdout, netdev)
+	return nil
+}
+
+func printNetDev(w io.Writer, netdev *networkd.NetDev) {
+	unitPath := networkd.SystemPath(netdev.Unit.Path)
+	if source := netdev.SourcePath(); source != netdev.Unit.Path {
+		unitPath = fmt.Sprintf("%s -> %s", unitPath, networkd.SystemPath(source))
+	}
+
+	printField(w, "Name", netdev.Name)
+	printField(w, "Kind", netdev.Kind)
+	printField(w, "Status", ansiColorStatus(netdev.Status))
+	printField(w, "State", netdev.Interface.OperState())
+	if netdev.Description != "" {
+		printField(w, "Description", netdev.Description)
+	}
+	printField(w, "Unit", unitPath)
+	printField(w, "Overrides", systemPaths(netdev.Shadowed)...)
+	printField(w, "Drop-ins", unitPaths(netdev.Dropins...)...)
+	printField(w, "Rename", unitPaths(netdev.RenameUnit, netdev.RenameNetworkUnit)...)
+
+	if netdev.ParentNetwork != nil {
+		printField(w, "Parent", netdev.ParentNetwork.Interface.Name)
+		printField(w, "Parent Network", networkPaths(netdev.ParentNetwork)...)
+	}
+	printField(w, "Network", networkPaths(netdev.Network)...)
+
+	netif := netdev.Interface.NetIf
+	if netif == nil {
+		return
+	}
+
+	printField(w, "Index", fmt.Sprint(netif.Index))
+	if link := netdev.Interface.Link; link != nil {
+		if link.Kind != "" {
+			printField(w, "Link Kind", link.Kind)
+		}
+		printField(w, "Link State", link.OperState)
+		if link.Parent != "" {
+			printField(w, "Link Parent", link.Parent)
+		}
+		if link.Master != "" {
+			printField(w, "Master", link.Master)
+		}
+		if link.VlanId != 0 {
+			printField(w, "VLAN Id", fmt.Sprint(link.VlanId))
+		}
+	}
+	printField(w, "MTU", fmt.Sprint(netif.MTU))
+	if len(netif.HardwareAddr) > 0 {
+		printField(w, "MAC", netif.HardwareAddr.String())
+	}
+	printField(w, "Flags", strings.ReplaceAll(netif.Flags.String(), "|", " "))
+
+	addrs, err := netif.Addrs()
+	if err != nil {
+		printField(w, "Addresses", fmt.Sprintf("unavailable (%v)", err))
+		return
+	}
+
+	var addresses []string
+	for _, addr := range addrs {
+		addresses = append(addresses, addr.String())
+	}
+	printField(w, "Addresses", addresses...)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/haboustak/linkctl/internal/networkd"
+)
+
+const defaultWaitTimeout = 30 * time.Second
+
+// waitFlag is a boolean flag that optionally accepts a timeout, so both
+// -wait and -wait=1m are valid.
+type waitFlag struct {
+	enabled bool
+	timeout time.Duration
+}
+
+func (self *waitFlag) String() string {
+	if self == nil || !self.enabled {
+		return "false"
+	}
+	return self.timeout.String()
+}
+
+func (self *waitFlag) Set(value string) error {
+	switch value {
+	case "true":
+		self.enabled = true
+		self.timeout = defaultWaitTimeout
+		return nil
+	case "false":
+		self.enabled = false
+		return nil
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid timeout %s", value)
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	self.enabled = true
+	self.timeout = timeout
+	return nil
+}
+
+func (self *waitFlag) IsBoolFlag() bool {
+	return true
+}
+
+// waitForLinks blocks until every link is ready or the timeout expires
+func waitForLinks(wait *waitFlag, names ...string) error {
+	if !wait.enabled {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), wait.timeout)
+	defer cancel()
+
+	for _, name := range names {
+		state, err := networkd.WaitForLink(ctx, name)
+		if err != nil {
+			return err
+		}
+		if state != nil {
+			fmt.Printf("%s: %s\n", name, state)
+		}
+	}
+
+	return nil
+}
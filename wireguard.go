@@ -0,0 +1,353 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haboustak/linkctl/internal/networkd"
+)
+
+var cmdWireGuard = &Command{
+	Name: "wg",
+	Run:  wireGuard,
+	Usage: `Usage:
+    linkctl [-h] wg create NAME [-port PORT] [-description TEXT]
+                               [-prefix PREFIX] [-enable] [-wait[=TIMEOUT]]
+    linkctl [-h] wg add-peer NAME PUBLICKEY [-allowed-ips IPS]
+                               [-endpoint HOST:PORT] [-keepalive SECONDS]
+                               [-preshared-key-file PATH]
+    linkctl [-h] wg remove-peer NAME PUBLICKEY
+    linkctl [-h] wg show NAME
+
+Manage WireGuard links and their peers
+
+Commands:
+    create          create a WireGuard link definition with a new private
+                    key, stored in /etc/linkctl/keys readable only by root
+                    and systemd-networkd
+    add-peer        add a peer to the link
+    remove-peer     remove the peer with PUBLICKEY from the link
+    show            show the configured peers and their handshake state
+
+Create options:
+    -description TEXT   description of the link
+    -enable             enable the link once it is created
+    -port PORT          UDP port to listen on (default auto)
+    -prefix PREFIX      prefix used to order the unit file (default 50)
+    -wait[=TIMEOUT]     with -enable, wait until systemd-networkd has
+                        configured the link, failing after TIMEOUT
+                        (default 30s)
+
+Peer options:
+    -allowed-ips IPS    comma separated addresses and prefixes routed to
+                        the peer
+    -endpoint HOST:PORT address of the peer
+    -keepalive SECONDS  interval between keepalive packets
+    -preshared-key-file PATH
+                        file holding a symmetric key shared with the peer
+
+Options:
+    -h      show this help
+`,
+}
+
+func wireGuard(self *Command) error {
+	args := self.Flags.Args()
+
+	if len(args) < 1 {
+		return errors.New("You must provide a wg operation, try \"linkctl wg -h\"")
+	}
+
+	switch args[0] {
+	case "create":
+		return wireGuardCreate(self, args[1:])
+	case "add-peer":
+		return wireGuardAddPeer(self, args[1:])
+	case "remove-peer":
+		return wireGuardRemovePeer(self, args[1:])
+	case "show":
+		return wireGuardShow(self, args[1:])
+	}
+
+	return fmt.Errorf("Unknown wg operation \"%s\", try \"linkctl wg -h\"", args[0])
+}
+
+// parseArgs parses flags that may appear before, between or after the
+// positional arguments, returning the positional arguments.
+func parseArgs(self *Command, flags *flag.FlagSet, args []string) ([]string, error) {
+	flags.Usage = func() {
+		printUsage(self.Usage)
+	}
+
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func wireGuardCreate(self *Command, args []string) error {
+	var config networkd.NetDevConfig
+	var port string
+	var enable bool
+	var wait waitFlag
+
+	flags := flag.NewFlagSet("create", flag.ExitOnError)
+	flags.StringVar(&config.Description, "description", "", "description of the link")
+	flags.BoolVar(&enable, "enable", false, "enable the link once it is created")
+	flags.StringVar(&port, "port", "", "UDP port to listen on")
+	flags.StringVar(&config.Prefix, "prefix", networkd.DefaultUnitPrefix, "prefix used to order the unit file")
+	flags.Var(&wait, "wait", "wait until the link is configured")
+
+	positional, err := parseArgs(self, flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("You must provide the name of the link to create")
+	}
+	config.Name = positional[0]
+
+	config.Settings = make(map[string]string)
+	if port != "" {
+		config.Settings["ListenPort"] = port
+	}
+
+	// The key is written after the unit, roll back the unit if that fails
+	if !enable {
+		tx := networkd.Begin()
+		if _, err := networkd.CreateWireGuard(&config); err != nil {
+			return tx.Rollback(err)
+		}
+		tx.Commit()
+	} else {
+		err = networkd.Apply(func() error {
+			netdev, err := networkd.CreateWireGuard(&config)
+			if err != nil {
+				return err
+			}
+			return netdev.Enable()
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if netdev, ok := networkd.GetNetDev(config.Name); ok {
+		if publicKey, err := netdev.PublicKey(); err == nil {
+			fmt.Printf("public key: %s\n", publicKey)
+		}
+	}
+
+	if !enable {
+		return nil
+	}
+	return waitForLinks(&wait, config.Name)
+}
+
+func wireGuardAddPeer(self *Command, args []string) error {
+	var peer networkd.WireGuardPeer
+	var allowedIPs string
+
+	flags := flag.NewFlagSet("add-peer", flag.ExitOnError)
+	flags.StringVar(&allowedIPs, "allowed-ips", "", "addresses and prefixes routed to the peer")
+	flags.StringVar(&peer.Endpoint, "endpoint", "", "address of the peer")
+	flags.StringVar(&peer.PersistentKeepalive, "keepalive", "", "interval between keepalive packets")
+	flags.StringVar(&peer.PresharedKeyFile, "preshared-key-file", "", "file holding a preshared key")
+
+	positional, err := parseArgs(self, flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return errors.New("You must provide the name of the link and the peer's public key")
+	}
+	peer.PublicKey = positional[1]
+
+	for _, ip := range strings.Split(allowedIPs, ",") {
+		if ip = strings.TrimSpace(ip); ip != "" {
+			peer.AllowedIPs = append(peer.AllowedIPs, ip)
+		}
+	}
+
+	netdev, err := getWireGuard(positional[0])
+	if err != nil {
+		return err
+	}
+
+	return networkd.Apply(func() error {
+		return netdev.AddPeer(&peer)
+	})
+}
+
+func wireGuardRemovePeer(self *Command, args []string) error {
+	flags := flag.NewFlagSet("remove-peer", flag.ExitOnError)
+
+	positional, err := parseArgs(self, flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return errors.New("You must provide the name of the link and the peer's public key")
+	}
+
+	netdev, err := getWireGuard(positional[0])
+	if err != nil {
+		return err
+	}
+
+	return networkd.Apply(func() error {
+		return netdev.RemovePeer(positional[1])
+	})
+}
+
+func wireGuardShow(self *Command, args []string) error {
+	flags := flag.NewFlagSet("show", flag.ExitOnError)
+
+	positional, err := parseArgs(self, flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("You must provide the name of the link to show")
+	}
+
+	netdev, err := getWireGuard(positional[0])
+	if err != nil {
+		return err
+	}
+
+	peers, err := netdev.WireGuardPeers()
+	if err != nil {
+		return err
+	}
+
+	// The kernel only knows about enabled links that networkd has created
+	device := &networkd.WireGuardDevice{}
+	if netdev.Interface.NetIf != nil {
+		if device, err = networkd.GetWireGuardDevice(netdev.Name); err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to read the state of %s: %v\n", netdev.Name, err)
+			device = &networkd.WireGuardDevice{}
+		}
+	}
+
+	publicKey := device.PublicKey
+	if publicKey == "" {
+		if publicKey, err = netdev.PublicKey(); err != nil {
+			publicKey = "(unavailable)"
+		}
+	}
+
+	fmt.Printf("interface: %s (%s)\n", netdev.Name, netdev.Status)
+	fmt.Printf("  public key: %s\n", publicKey)
+	if device.ListenPort != 0 {
+		fmt.Printf("  listening port: %d\n", device.ListenPort)
+	}
+
+	for _, peer := range peers {
+		state := device.Peer(peer.PublicKey)
+		fmt.Println()
+		if state == nil {
+			fmt.Printf("peer: %s (inactive)\n", peer.PublicKey)
+			printPeerConfig(peer)
+			continue
+		}
+
+		fmt.Printf("peer: %s\n", peer.PublicKey)
+		printPeerState(state)
+	}
+
+	for _, state := range device.Peers {
+		if configuredPeer(peers, state.PublicKey) {
+			continue
+		}
+		fmt.Println()
+		fmt.Printf("peer: %s (unconfigured)\n", state.PublicKey)
+		printPeerState(state)
+	}
+
+	return nil
+}
+
+func getWireGuard(name string) (*networkd.NetDev, error) {
+	netdev, ok := networkd.GetNetDev(name)
+	if !ok {
+		return nil, fmt.Errorf("No link with the name %s", name)
+	}
+	if netdev.Kind != "wireguard" {
+		return nil, fmt.Errorf("The link %s is a %s link, not wireguard", name, netdev.Kind)
+	}
+	return netdev, nil
+}
+
+func configuredPeer(peers []*networkd.WireGuardPeer, publicKey string) bool {
+	for _, peer := range peers {
+		if peer.PublicKey == publicKey {
+			return true
+		}
+	}
+	return false
+}
+
+func printPeerConfig(peer *networkd.WireGuardPeer) {
+	if peer.Endpoint != "" {
+		fmt.Printf("  endpoint: %s\n", peer.Endpoint)
+	}
+	if len(peer.AllowedIPs) > 0 {
+		fmt.Printf("  allowed ips: %s\n", strings.Join(peer.AllowedIPs, ", "))
+	}
+	if peer.PersistentKeepalive != "" && peer.PersistentKeepalive != "0" && peer.PersistentKeepalive != "off" {
+		fmt.Printf("  persistent keepalive: every %s seconds\n", peer.PersistentKeepalive)
+	}
+}
+
+func printPeerState(peer *networkd.WireGuardPeerState) {
+	if peer.Endpoint != "" {
+		fmt.Printf("  endpoint: %s\n", peer.Endpoint)
+	}
+	allowedIPs := "(none)"
+	if len(peer.AllowedIPs) > 0 {
+		allowedIPs = strings.Join(peer.AllowedIPs, ", ")
+	}
+	fmt.Printf("  allowed ips: %s\n", allowedIPs)
+
+	if peer.LastHandshake.IsZero() {
+		fmt.Printf("  latest handshake: never\n")
+	} else {
+		ago := time.Since(peer.LastHandshake).Truncate(time.Second)
+		fmt.Printf("  latest handshake: %s ago\n", ago)
+	}
+
+	fmt.Printf("  transfer: %s received, %s sent\n", formatBytes(peer.RxBytes), formatBytes(peer.TxBytes))
+	if peer.PersistentKeepalive > 0 {
+		fmt.Printf("  persistent keepalive: every %d seconds\n", peer.PersistentKeepalive)
+	}
+}
+
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return strconv.FormatUint(n, 10) + " B"
+	}
+
+	value := float64(n)
+	suffixes := []string{"KiB", "MiB", "GiB", "TiB"}
+	i := -1
+	for value >= unit && i < len(suffixes)-1 {
+		value /= unit
+		i++
+	}
+	return fmt.Sprintf("%.2f %s", value, suffixes[i])
+}